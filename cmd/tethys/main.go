@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,19 +17,56 @@ import (
 
 func main() {
 	listenAddr := getenv("TETHYS_LISTEN_ADDR", ":8080")
+	adminAddr := adminListenAddr(os.Getenv("TETHYS_ADMIN_LISTEN_ADDR"))
 	dataDir := getenv("TETHYS_DATA_DIR", "./data")
 	dbPath := filepath.Join(dataDir, "tethys.sqlite")
+	heartbeat, err := time.ParseDuration(getenv("TETHYS_SSE_HEARTBEAT", "15s"))
+	if err != nil {
+		log.Fatalf("TETHYS_SSE_HEARTBEAT: %v", err)
+	}
+
+	loc, err := time.LoadLocation(getenv("TETHYS_TZ", "UTC"))
+	if err != nil {
+		log.Fatalf("TETHYS_TZ: %v", err)
+	}
+
+	// set but empty allows crawling everything
+	var robotsDisallow []string
+	if v, ok := os.LookupEnv("TETHYS_ROBOTS_DISALLOW"); ok {
+		robotsDisallow = append([]string{}, strings.Fields(v)...)
+	}
 
-	application, err := app.New(dataDir, dbPath)
+	var templateDir string
+	if os.Getenv("TETHYS_DEV") == "1" {
+		templateDir = getenv("TETHYS_TEMPLATE_DIR", "internal/web/templates")
+		log.Printf("dev mode: loading templates from %s", templateDir)
+	}
+
+	application, err := app.New(dataDir, dbPath, app.Options{
+		SeparateAdmin:     adminAddr != "",
+		EngineAllowedDirs: filepath.SplitList(os.Getenv("TETHYS_ENGINE_ALLOWED_DIRS")),
+		SSEHeartbeat:      heartbeat,
+		TemplateDir:       templateDir,
+		Location:          loc,
+		RobotsDisallow:    robotsDisallow,
+		SiteTitle:         strings.TrimSpace(os.Getenv("TETHYS_SITE_TITLE")),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer application.Close()
 
-	server := &http.Server{
+	servers := []*http.Server{{
 		Addr:              listenAddr,
 		Handler:           application.Router(),
 		ReadHeaderTimeout: 10 * time.Second,
+	}}
+	if adminAddr != "" {
+		servers = append(servers, &http.Server{
+			Addr:              adminAddr,
+			Handler:           application.AdminRouter(),
+			ReadHeaderTimeout: 10 * time.Second,
+		})
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -37,15 +76,39 @@ func main() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		_ = server.Shutdown(shutdownCtx)
+		for _, server := range servers {
+			_ = server.Shutdown(shutdownCtx)
+		}
 	}()
 
+	if adminAddr != "" {
+		go func() {
+			log.Printf("tethys admin listening on %s", adminAddr)
+			if err := servers[1].ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
 	log.Printf("tethys listening on %s", listenAddr)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := servers[0].ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
 
+// adminListenAddr binds a bare ":port" to localhost, so the admin listener is
+// only reachable from outside when a host is given explicitly.
+func adminListenAddr(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
 func getenv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
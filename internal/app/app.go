@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"tethys/internal/db"
 	"tethys/internal/engine"
@@ -16,13 +17,39 @@ import (
 type App struct {
 	store *db.Store
 
-	runner *engine.Runner
-	mux    *http.ServeMux
+	runner   *engine.Runner
+	analyzer *engine.Analyzer
+	mux      http.Handler
+	adminMux http.Handler
 
 	closeOnce sync.Once
 }
 
-func New(dataDir string, dbPath string) (*App, error) {
+type Options struct {
+	// SeparateAdmin moves the admin pages off the public router onto
+	// AdminRouter.
+	SeparateAdmin bool
+	// EngineAllowedDirs, if set, restricts engine paths entered in the admin
+	// UI to these directories (the engines upload dir is always allowed).
+	EngineAllowedDirs []string
+	// SSEHeartbeat is the keepalive interval of the live event stream;
+	// 0 disables it.
+	SSEHeartbeat time.Duration
+	// TemplateDir, if set, is read on every request instead of the embedded
+	// templates (development mode).
+	TemplateDir string
+	// Location is the time zone timestamps are displayed in; nil means UTC.
+	Location *time.Location
+	// SiteTitle names the instance in page titles and the header; empty
+	// keeps "tethys".
+	SiteTitle string
+	// RobotsDisallow lists the path prefixes /robots.txt disallows; nil
+	// means web.DefaultRobotsDisallow, empty allows everything.
+	RobotsDisallow []string
+}
+
+// New opens the data dir and starts the runner.
+func New(dataDir string, dbPath string, opts Options) (*App, error) {
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
@@ -34,23 +61,54 @@ func New(dataDir string, dbPath string) (*App, error) {
 	if err := os.MkdirAll(booksDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create books dir: %w", err)
 	}
+	suitesDir := filepath.Join(dataDir, "suites")
+	if err := os.MkdirAll(suitesDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create suites dir: %w", err)
+	}
 
 	sqlDB, err := db.Open(dbPath)
 	if err != nil {
 		return nil, err
 	}
 	b := engine.NewBroadcaster()
+	b.SetHeartbeat(opts.SSEHeartbeat)
 	r := engine.NewRunner(sqlDB, b)
-	r.Start(context.Background())
 	an := engine.NewAnalyzer(sqlDB)
 
-	h := web.NewHandler(sqlDB, r, b, an, enginesDir, booksDir)
+	h := web.NewHandler(sqlDB, r, b, an, enginesDir, booksDir, suitesDir, opts.EngineAllowedDirs)
+	if opts.Location != nil {
+		h.SetLocation(opts.Location)
+	}
+	if opts.SiteTitle != "" {
+		h.SetSiteTitle(opts.SiteTitle)
+	}
+	if opts.RobotsDisallow != nil {
+		h.SetRobotsDisallow(opts.RobotsDisallow)
+	}
+	if opts.TemplateDir != "" {
+		if err := h.SetTemplateDir(opts.TemplateDir); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("load templates: %w", err)
+		}
+	}
 	mux := http.NewServeMux()
-	h.RegisterRoutes(mux)
+	var adminMux http.Handler
+	if opts.SeparateAdmin {
+		h.RegisterPublicRoutes(mux)
+		admin := http.NewServeMux()
+		h.RegisterAdminRoutes(admin)
+		adminMux = web.LogRequests(admin)
+	} else {
+		h.RegisterRoutes(mux)
+	}
 
+	r.Start(context.Background())
 	return &App{
-		runner: r,
-		mux:    mux,
+		store:    sqlDB,
+		runner:   r,
+		analyzer: an,
+		mux:      web.LogRequests(mux),
+		adminMux: adminMux,
 	}, nil
 }
 
@@ -58,9 +116,18 @@ func (a *App) Router() http.Handler {
 	return a.mux
 }
 
+// AdminRouter is nil unless New was asked for a separate admin router.
+func (a *App) AdminRouter() http.Handler {
+	if a.adminMux == nil {
+		return nil
+	}
+	return a.adminMux
+}
+
 func (a *App) Close() {
 	a.closeOnce.Do(func() {
 		a.runner.Stop()
+		a.analyzer.Close()
 		_ = a.store.Close()
 	})
 }
@@ -49,6 +49,12 @@ func ZobristKey(pos *chess.Position) uint64 {
 }
 
 func (b *Book) Lookup(pos *chess.Position) (string, bool) {
+	return b.LookupRand(pos, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// LookupRand picks a weighted book move using rng, so a seeded rng gives
+// the same line every time.
+func (b *Book) LookupRand(pos *chess.Position, rng *rand.Rand) (string, bool) {
 	if b == nil || len(b.entries) == 0 {
 		return "", false
 	}
@@ -75,8 +81,7 @@ func (b *Book) Lookup(pos *chess.Position) (string, bool) {
 		return decodeMove(choices[0].move), true
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	pick := r.Intn(total)
+	pick := rng.Intn(total)
 	acc := 0
 	for _, c := range choices {
 		acc += int(c.weight)
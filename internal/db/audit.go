@@ -0,0 +1,32 @@
+package db
+
+import "context"
+
+type AuditEntry struct {
+	ID     int64  `db:"id"`
+	At     string `db:"at"`
+	Remote string `db:"remote"`
+	Action string `db:"action"`
+	Detail string `db:"detail"`
+}
+
+// record an admin action; remote is the client address it came from
+func (s *Store) InsertAudit(ctx context.Context, remote, action, detail string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (remote, action, detail)
+		VALUES (?, ?, ?)
+	`, remote, action, detail)
+	return err
+}
+
+// the most recent audit entries, newest first
+func (s *Store) ListAudit(ctx context.Context, limit int) ([]AuditEntry, error) {
+	var out []AuditEntry
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT id, at, remote, action, detail
+		FROM audit_log
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	return out, err
+}
@@ -8,6 +8,7 @@ type EngineLog struct {
 	EngineID  int64  `db:"engine_id"`
 	ElapsedMS int64  `db:"elapsed_ms"`
 	Log       string `db:"log"`
+	PV        string `db:"pv"`
 }
 
 func (s *Store) InsertEngineLogs(ctx context.Context, gameID int64, logs []EngineLog) error {
@@ -25,8 +26,8 @@ func (s *Store) InsertEngineLogs(ctx context.Context, gameID int64, logs []Engin
 	}()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO engine_logs (game_id, ply, engine_id, elapsed_ms, log)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO engine_logs (game_id, ply, engine_id, elapsed_ms, log, pv)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -34,7 +35,7 @@ func (s *Store) InsertEngineLogs(ctx context.Context, gameID int64, logs []Engin
 	defer stmt.Close()
 
 	for _, entry := range logs {
-		if _, err = stmt.ExecContext(ctx, gameID, entry.Ply, entry.EngineID, entry.ElapsedMS, entry.Log); err != nil {
+		if _, err = stmt.ExecContext(ctx, gameID, entry.Ply, entry.EngineID, entry.ElapsedMS, entry.Log, entry.PV); err != nil {
 			return err
 		}
 	}
@@ -42,10 +43,31 @@ func (s *Store) InsertEngineLogs(ctx context.Context, gameID int64, logs []Engin
 	return tx.Commit()
 }
 
+// EngineComputeTime sums each engine's logged thinking time in milliseconds.
+// Moves logged before elapsed_ms was recorded count as 0.
+func (s *Store) EngineComputeTime(ctx context.Context) (map[int64]int64, error) {
+	var rows []struct {
+		EngineID int64 `db:"engine_id"`
+		Total    int64 `db:"total"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT engine_id, SUM(elapsed_ms) AS total
+		FROM engine_logs
+		GROUP BY engine_id
+	`); err != nil {
+		return nil, err
+	}
+	out := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		out[row.EngineID] = row.Total
+	}
+	return out, nil
+}
+
 func (s *Store) ListEngineLogsByGame(ctx context.Context, gameID int64) ([]EngineLog, error) {
 	var out []EngineLog
 	err := s.db.SelectContext(ctx, &out, `
-		SELECT game_id, ply, engine_id, elapsed_ms, log
+		SELECT game_id, ply, engine_id, elapsed_ms, log, pv
 		FROM engine_logs
 		WHERE game_id = ?
 		ORDER BY ply ASC, engine_id ASC
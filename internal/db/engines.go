@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"strings"
 )
 
@@ -9,7 +10,8 @@ import (
 func (s *Store) ListEngines(ctx context.Context) ([]Engine, error) {
 	var out []Engine
 	err := s.db.SelectContext(ctx, &out, `
-		SELECT id, name, engine_path, engine_args, engine_init, engine_elo
+		SELECT id, name, engine_path, engine_args, engine_init, engine_elo, skip_newgame, strength_elo, paused, notes, tags,
+			bench_nodes, bench_nps, bench_at
 		FROM players
 		ORDER BY engine_elo DESC, id ASC
 	`)
@@ -19,9 +21,10 @@ func (s *Store) ListEngines(ctx context.Context) ([]Engine, error) {
 // add new engine, returning its newly assigned ID
 func (s *Store) InsertEngine(ctx context.Context, e Engine) (int64, error) {
 	e.Path = strings.TrimSpace(e.Path)
+	e.Tags = NormalizeTags(e.Tags)
 	res, err := s.db.NamedExecContext(ctx, `
-		INSERT INTO players (name, engine_path, engine_args, engine_init)
-		VALUES (:name, :engine_path, :engine_args, :engine_init)
+		INSERT INTO players (name, engine_path, engine_args, engine_init, skip_newgame, strength_elo, notes, tags)
+		VALUES (:name, :engine_path, :engine_args, :engine_init, :skip_newgame, :strength_elo, :notes, :tags)
 	`, e)
 	if err != nil {
 		return 0, err
@@ -33,34 +36,114 @@ func (s *Store) InsertEngine(ctx context.Context, e Engine) (int64, error) {
 func (s *Store) EngineByID(ctx context.Context, id int64) (Engine, error) {
 	var e Engine
 	err := s.db.GetContext(ctx, &e, `
-		SELECT id, name, engine_path, engine_args, engine_init, engine_elo
+		SELECT id, name, engine_path, engine_args, engine_init, engine_elo, skip_newgame, strength_elo, paused, notes, tags,
+			bench_nodes, bench_nps, bench_at
 		FROM players
 		WHERE id = ?
 	`, id)
 	return e, err
 }
 
+// report whether an engine with this ID exists
+func (s *Store) EngineExists(ctx context.Context, id int64) (bool, error) {
+	var n int
+	if err := s.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM players WHERE id = ?`, id); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
 // find an engine by its ID and update its details
 func (s *Store) UpdateEngine(ctx context.Context, e Engine) error {
 	e.Path = strings.TrimSpace(e.Path)
+	e.Tags = NormalizeTags(e.Tags)
 	_, err := s.db.NamedExecContext(ctx, `
 		UPDATE players
 		SET name = :name,
 			engine_path = :engine_path,
 			engine_args = :engine_args,
-			engine_init = :engine_init
+			engine_init = :engine_init,
+			skip_newgame = :skip_newgame,
+			strength_elo = :strength_elo,
+			notes = :notes,
+			tags = :tags
 		WHERE id = :id
 	`, e)
 	return err
 }
 
+// record the result of a benchmark run for an engine
+func (s *Store) SetEngineBench(ctx context.Context, id int64, nodes, nps int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE players
+		SET bench_nodes = ?, bench_nps = ?, bench_at = strftime('%Y-%m-%dT%H:%M:%fZ','now')
+		WHERE id = ?
+	`, nodes, nps, id)
+	return err
+}
+
+// SetEnginePaused pauses or resumes scheduling games for an engine.
+func (s *Store) SetEnginePaused(ctx context.Context, id int64, paused bool) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE players SET paused = ? WHERE id = ?`, paused, id)
+	return err
+}
+
 // delete a single engine by its ID
 func (s *Store) DeleteEngine(ctx context.Context, id int64) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, id)
 	return err
 }
 
-// replace all engines ELO ratings
+// MergeEngines moves everything that references mergeID (games, queued
+// games, engine logs, evals, test suite results and the analysis engine
+// setting) over to keepID and deletes mergeID, all in one transaction.
+func (s *Store) MergeEngines(ctx context.Context, keepID, mergeID int64) error {
+	if keepID == mergeID {
+		return fmt.Errorf("cannot merge an engine into itself")
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var n int
+	if err = tx.GetContext(ctx, &n, `SELECT COUNT(*) FROM players WHERE id IN (?, ?)`, keepID, mergeID); err != nil {
+		return err
+	}
+	if n != 2 {
+		err = fmt.Errorf("unknown engine")
+		return err
+	}
+	stmts := []string{
+		`UPDATE games SET white_player_id = ? WHERE white_player_id = ?`,
+		`UPDATE games SET black_player_id = ? WHERE black_player_id = ?`,
+		`UPDATE game_queue SET white_player_id = ? WHERE white_player_id = ?`,
+		`UPDATE game_queue SET black_player_id = ? WHERE black_player_id = ?`,
+		`UPDATE engine_logs SET engine_id = ? WHERE engine_id = ?`,
+		`UPDATE evals SET engine_id = ? WHERE engine_id = ?`,
+		`UPDATE suite_positions SET engine_id = ? WHERE engine_id = ?`,
+		`UPDATE suite_runs SET engine_id = ? WHERE engine_id = ?`,
+		`UPDATE settings SET value = ? WHERE key = 'analysis_engine_id' AND value = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt, keepID, mergeID); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, mergeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceEngineElos replaces all engines ELO ratings. A rating that moved at
+// least half a point since the engine's last history entry is also added to
+// its history.
 func (s *Store) ReplaceEngineElos(ctx context.Context, elos map[int64]float64) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -77,11 +160,23 @@ func (s *Store) ReplaceEngineElos(ctx context.Context, elos map[int64]float64) e
 		return err
 	}
 	defer stmt.Close()
+	history, err := tx.PrepareContext(ctx, `
+		INSERT INTO elo_history (engine_id, elo)
+		SELECT ?1, ?2
+		WHERE abs(?2 - coalesce((SELECT elo FROM elo_history WHERE engine_id = ?1 ORDER BY id DESC LIMIT 1), 1e9)) >= 0.5
+	`)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
 
 	for id, elo := range elos {
 		if _, err = stmt.ExecContext(ctx, elo, id); err != nil {
 			return err
 		}
+		if _, err = history.ExecContext(ctx, id, elo); err != nil {
+			return err
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -97,23 +192,30 @@ func (s *Store) EngineIDByName(ctx context.Context, name string) (int64, error)
 	return id, err
 }
 
-// TODO: I dont think this function is quite correct w.r.t. mirror matchups
+// EngineGameCounts maps each engine to the number of games it played. A
+// self-play game (white == black) counts once for that engine.
 func (s *Store) EngineGameCounts(ctx context.Context) (map[int64]int, error) {
 	type countRow struct {
 		ID    int64 `db:"id"`
 		Count int   `db:"count"`
 	}
 	var rows []countRow
+	// UNION (not UNION ALL) drops the duplicate (game, engine) pair that a
+	// self-play game produces
 	if err := s.db.SelectContext(ctx, &rows, `
-		SELECT white_player_id AS id, COUNT(*) AS count FROM games GROUP BY white_player_id
-		UNION ALL
-		SELECT black_player_id AS id, COUNT(*) AS count FROM games GROUP BY black_player_id
+		SELECT id, COUNT(*) AS count
+		FROM (
+			SELECT id AS game_id, white_player_id AS id FROM games
+			UNION
+			SELECT id AS game_id, black_player_id AS id FROM games
+		)
+		GROUP BY id
 	`); err != nil {
 		return nil, err
 	}
-	counts := make(map[int64]int)
+	counts := make(map[int64]int, len(rows))
 	for _, row := range rows {
-		counts[row.ID] += row.Count
+		counts[row.ID] = row.Count
 	}
 	return counts, nil
 }
@@ -128,8 +230,10 @@ func (s *Store) DeleteGamesByEngine(ctx context.Context, engineID int64) (int64,
 	}
 	rows, err := res.RowsAffected()
 	if err != nil {
+		s.gameCount.invalidate()
 		return 0, err
 	}
+	s.gameCount.add(-int(rows))
 	return rows, nil
 }
 
@@ -141,3 +245,47 @@ func (s *Store) CountEngines(ctx context.Context) (int, error) {
 	`)
 	return count, err
 }
+
+// NormalizeTags lowercases, trims and dedupes a comma-separated tag list.
+func NormalizeTags(raw string) string {
+	return strings.Join(splitTags(raw), ",")
+}
+
+func splitTags(raw string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// TagList returns the engine's tags.
+func (e Engine) TagList() []string {
+	return splitTags(e.Tags)
+}
+
+// HasTag reports whether the engine carries tag (case-insensitive).
+func (e Engine) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range e.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// an engine's rating history, oldest first
+func (s *Store) EloHistory(ctx context.Context, engineID int64) ([]EloPoint, error) {
+	var out []EloPoint
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT at, elo FROM elo_history WHERE engine_id = ? ORDER BY id
+	`, engineID)
+	return out, err
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func insertTestEngine(t *testing.T, s *Store, name string) int64 {
+	t.Helper()
+	id, err := s.InsertEngine(context.Background(), Engine{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func insertTestGame(t *testing.T, s *Store, white, black int64, result string) {
+	t.Helper()
+	if _, err := s.InsertFinishedGame(context.Background(), white, black, 100, "movetime:100", 0, "", result, "Checkmate", "e2e4", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngineGameCounts(t *testing.T) {
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	c := insertTestEngine(t, s, "c")
+	unused := insertTestEngine(t, s, "unused")
+
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, b, a, "0-1")
+	insertTestGame(t, s, a, a, "1/2-1/2") // self-play counts once for a
+	insertTestGame(t, s, a, a, "1-0")
+	insertTestGame(t, s, c, c, "0-1")
+
+	counts, err := s.EngineGameCounts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]int{a: 4, b: 2, c: 1}
+	for id, n := range want {
+		if counts[id] != n {
+			t.Errorf("engine %d: got %d games, want %d", id, counts[id], n)
+		}
+	}
+	if n, ok := counts[unused]; ok {
+		t.Errorf("unused engine: got %d games, want no entry", n)
+	}
+	if len(counts) != len(want) {
+		t.Errorf("got %d entries, want %d: %v", len(counts), len(want), counts)
+	}
+}
+
+func TestMergeEngines(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	dup := insertTestEngine(t, s, "a (again)")
+	b := insertTestEngine(t, s, "b")
+
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, dup, b, "0-1")
+	insertTestGame(t, s, b, dup, "1/2-1/2")
+	insertTestGame(t, s, a, dup, "1-0") // becomes self-play
+	if err := s.UpsertEval(ctx, Eval{ZobristKey: 1, FEN: "x", EngineID: dup}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.MergeEngines(ctx, a, dup); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.EngineExists(ctx, dup); err != nil || ok {
+		t.Fatalf("merged engine still exists (err %v)", err)
+	}
+	counts, err := s.EngineGameCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[a] != 4 || counts[b] != 3 {
+		t.Errorf("got counts %v, want a=4 b=3", counts)
+	}
+	if n, err := s.EngineEvalCount(ctx, a); err != nil || n != 1 {
+		t.Errorf("got %d evals for a (err %v), want 1", n, err)
+	}
+
+	if err := s.MergeEngines(ctx, a, a); err == nil {
+		t.Error("merging an engine into itself should fail")
+	}
+	if err := s.MergeEngines(ctx, a, dup); err == nil {
+		t.Error("merging a deleted engine should fail")
+	}
+}
+
+func TestEngineComputeTime(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	insertTestEngine(t, s, "idle")
+
+	for _, elapsed := range []int64{100, 250} {
+		id, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "1-0", "Checkmate", "e2e4 e7e5", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.InsertEngineLogs(ctx, id, []EngineLog{
+			{Ply: 0, EngineID: a, ElapsedMS: elapsed, Log: "bestmove e2e4"},
+			{Ply: 1, EngineID: b, ElapsedMS: 2 * elapsed, Log: "bestmove e7e5"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.EngineComputeTime(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[a] != 350 || got[b] != 700 {
+		t.Errorf("compute time = %v, want %d:350 %d:700", got, a, b)
+	}
+}
+
+func TestSetEnginePaused(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	id := insertTestEngine(t, s, "a")
+	if err := s.SetEnginePaused(ctx, id, true); err != nil {
+		t.Fatal(err)
+	}
+	e, err := s.EngineByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Paused {
+		t.Fatal("engine not paused")
+	}
+	// saving the engine form must not resume it
+	if err := s.UpdateEngine(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	engines, err := s.ListEngines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(engines) != 1 || !engines[0].Paused {
+		t.Errorf("ListEngines = %+v, want the engine paused", engines)
+	}
+}
+
+func TestEloHistory(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	for _, elo := range []float64{10, 10.2, 25, 25} {
+		if err := s.ReplaceEngineElos(ctx, map[int64]float64{a: elo}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := s.EloHistory(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Elo != 10 || history[1].Elo != 25 {
+		t.Fatalf("history = %+v, want 10 then 25", history)
+	}
+	eng, err := s.EngineByID(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eng.Elo != 25 {
+		t.Errorf("elo = %v, want 25", eng.Elo)
+	}
+}
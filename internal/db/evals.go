@@ -28,6 +28,13 @@ func (s *Store) UpsertEval(ctx context.Context, e Eval) error {
 	return err
 }
 
+// number of evaluations produced by an engine
+func (s *Store) EngineEvalCount(ctx context.Context, engineID int64) (int, error) {
+	var n int
+	err := s.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM evals WHERE engine_id = ?`, engineID)
+	return n, err
+}
+
 func (s *Store) DeleteEvalsByEngine(ctx context.Context, engineID int64) (int64, error) {
 	res, err := s.db.ExecContext(ctx, `DELETE FROM evals WHERE engine_id = ?`, engineID)
 	if err != nil {
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// gameCounter caches COUNT(*) over games. Writers adjust it by the rows they
+// insert or delete; until the first read it isn't loaded and they skip it.
+type gameCounter struct {
+	mu    sync.Mutex
+	n     int
+	valid bool
+	// gen changes on every adjustment, so a count that raced a write isn't
+	// cached
+	gen uint64
+}
+
+func (c *gameCounter) add(delta int) {
+	c.mu.Lock()
+	c.n += delta
+	c.gen++
+	c.mu.Unlock()
+}
+
+// invalidate drops the cached count, for writes of unknown size.
+func (c *gameCounter) invalidate() {
+	c.mu.Lock()
+	c.valid = false
+	c.gen++
+	c.mu.Unlock()
+}
+
+// CachedGameCount is CountGames without the table scan after the first call.
+func (s *Store) CachedGameCount(ctx context.Context) (int, error) {
+	c := &s.gameCount
+	c.mu.Lock()
+	if c.valid {
+		n := c.n
+		c.mu.Unlock()
+		return n, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	n, err := s.CountGames(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	if c.gen == gen {
+		c.n = n
+		c.valid = true
+	}
+	c.mu.Unlock()
+	return n, nil
+}
@@ -1,16 +1,32 @@
 package db
 
-import "context"
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+
+	"tethys/internal/book"
+	"tethys/internal/eco"
+)
 
 // Add a finished game to the database. Returns the inserted games ID.
-func (s *Store) InsertFinishedGame(ctx context.Context, whiteID int64, blackID int64, movetimeMS int, bookPath string, result, termination, movesUCI string, bookPlies int) (int64, error) {
+func (s *Store) InsertFinishedGame(ctx context.Context, whiteID int64, blackID int64, movetimeMS int, searchLimit string, seed int64, bookPath string, result, termination, movesUCI string, bookPlies int) (int64, error) {
+	opening, _ := eco.Classify(movesUCI)
 	res, err := s.db.ExecContext(ctx, `
-		INSERT INTO games (white_player_id, black_player_id, movetime_ms, book_path, result, termination, moves_uci, book_plies)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, whiteID, blackID, movetimeMS, bookPath, result, termination, movesUCI, bookPlies)
+		INSERT INTO games (white_player_id, black_player_id, movetime_ms, search_limit, seed, book_path, result, termination, moves_uci, book_plies, eco, opening)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, whiteID, blackID, movetimeMS, searchLimit, seed, bookPath, result, NormalizeTermination(termination), movesUCI, bookPlies, opening.ECO, opening.Name)
 	if err != nil {
 		return 0, err
 	}
+	s.gameCount.add(1)
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
@@ -27,11 +43,15 @@ func (s *Store) ListFinishedGames(ctx context.Context, limit int) ([]GameDetail,
 			w.name AS white,
 			b.name AS black,
 			g.movetime_ms,
+			g.search_limit,
+			g.seed,
 			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
 			g.termination AS termination,
 			g.moves_uci,
 			g.ply_count,
-			g.book_plies
+			g.book_plies,
+			g.eco,
+			g.opening
 		FROM games g
 		LEFT JOIN players w ON g.white_player_id = w.id
 		LEFT JOIN players b ON g.black_player_id = b.id
@@ -41,6 +61,35 @@ func (s *Store) ListFinishedGames(ctx context.Context, limit int) ([]GameDetail,
 	return out, err
 }
 
+// RecentGamesForEngine lists the most recent games the engine played with
+// either color, newest first.
+func (s *Store) RecentGamesForEngine(ctx context.Context, engineID int64, limit int) ([]GameDetail, error) {
+	var out []GameDetail
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT g.id,
+			g.played_at,
+			w.name AS white,
+			b.name AS black,
+			g.movetime_ms,
+			g.search_limit,
+			g.seed,
+			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
+			g.termination AS termination,
+			g.moves_uci,
+			g.ply_count,
+			g.book_plies,
+			g.eco,
+			g.opening
+		FROM games g
+		LEFT JOIN players w ON g.white_player_id = w.id
+		LEFT JOIN players b ON g.black_player_id = b.id
+		WHERE g.white_player_id = ? OR g.black_player_id = ?
+		ORDER BY g.id DESC
+		LIMIT ?
+	`, engineID, engineID, limit)
+	return out, err
+}
+
 func (s *Store) ListFinishedGamesMoves(ctx context.Context, limit int) ([]GameMovesRow, error) {
 	var out []GameMovesRow
 	err := s.db.SelectContext(ctx, &out, `
@@ -87,11 +136,15 @@ func (s *Store) GetGame(ctx context.Context, id int64) (GameDetail, error) {
 			w.name AS white,
 			b.name AS black,
 			g.movetime_ms,
+			g.search_limit,
+			g.seed,
 			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
 			g.termination AS termination,
 			g.moves_uci,
 			g.ply_count,
-			g.book_plies
+			g.book_plies,
+			g.eco,
+			g.opening
 		FROM games g
 		LEFT JOIN players w ON g.white_player_id = w.id
 		LEFT JOIN players b ON g.black_player_id = b.id
@@ -100,7 +153,71 @@ func (s *Store) GetGame(ctx context.Context, id int64) (GameDetail, error) {
 	return gd, err
 }
 
+// GameHighlights are the games worth a look on the games page. A field is
+// nil when no game qualifies.
+type GameHighlights struct {
+	ShortestDecisive *GameDetail
+	Longest          *GameDetail
+	LatestDecisive   *GameDetail
+}
+
+// GameHighlights finds the shortest decisive game, the longest game and the
+// most recent decisive game. Ties go to the earlier game for length.
+func (s *Store) GameHighlights(ctx context.Context) (GameHighlights, error) {
+	var hl GameHighlights
+	queries := []struct {
+		dst   **GameDetail
+		where string
+		order string
+	}{
+		{&hl.ShortestDecisive, "g.result IN ('1-0', '0-1')", "g.ply_count ASC, g.id ASC"},
+		{&hl.Longest, "g.result <> ''", "g.ply_count DESC, g.id ASC"},
+		{&hl.LatestDecisive, "g.result IN ('1-0', '0-1')", "g.id DESC"},
+	}
+	for _, q := range queries {
+		var gd GameDetail
+		err := s.db.GetContext(ctx, &gd, `
+			SELECT g.id,
+				g.played_at,
+				w.name AS white,
+				b.name AS black,
+				g.movetime_ms,
+				g.search_limit,
+				g.seed,
+				g.result,
+				g.termination AS termination,
+				g.moves_uci,
+				g.ply_count,
+				g.book_plies,
+				g.eco,
+				g.opening
+			FROM games g
+			LEFT JOIN players w ON g.white_player_id = w.id
+			LEFT JOIN players b ON g.black_player_id = b.id
+			WHERE `+q.where+`
+			ORDER BY `+q.order+`
+			LIMIT 1
+		`)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return GameHighlights{}, err
+		}
+		*q.dst = &gd
+	}
+	return hl, nil
+}
+
 // universal search function
+// searchOrders whitelists the ORDER BY clauses SearchGames accepts. Ties
+// fall back to id so paging stays stable.
+var searchOrders = map[string]string{
+	"id":    "g.id DESC",
+	"date":  "g.played_at DESC, g.id DESC",
+	"plies": "g.ply_count DESC, g.id DESC",
+}
+
 func (s *Store) SearchGames(ctx context.Context, filter GameSearchFilter, limit int) (int, []GameDetail, error) {
 	if limit <= 0 {
 		limit = 20
@@ -127,17 +244,59 @@ func (s *Store) SearchGames(ctx context.Context, filter GameSearchFilter, limit
 		where += " AND (white_player_id = ? OR black_player_id = ?)"
 		args = append(args, filter.EngineID, filter.EngineID)
 	}
-	if filter.MovetimeMS > 0 {
-		where += " AND g.movetime_ms = ?"
-		args = append(args, filter.MovetimeMS)
+	if filter.SearchLimit != "" {
+		where += " AND g.search_limit = ?"
+		args = append(args, filter.SearchLimit)
 	}
 	if filter.Result != "" {
 		where += " AND (CASE WHEN result = '' THEN '*' ELSE result END) = ?"
 		args = append(args, filter.Result)
 	}
+	switch filter.Status {
+	case "":
+		if !filter.IncludeUnfinished && filter.Result != "*" {
+			where += " AND result <> ''"
+		}
+	case GameStatusFinished:
+		where += " AND result <> ''"
+	case GameStatusAborted:
+		where += " AND result = '' AND termination = ?"
+		args = append(args, TerminationAborted)
+	case GameStatusUnfinished:
+		where += " AND result = '' AND termination <> ?"
+		args = append(args, TerminationAborted)
+	default:
+		return 0, nil, fmt.Errorf("unknown game status %q", filter.Status)
+	}
 	if filter.Termination != "" {
 		where += " AND termination = ?"
-		args = append(args, filter.Termination)
+		args = append(args, NormalizeTermination(filter.Termination))
+	}
+	if filter.OpeningPrefix != "" {
+		// UCI moves never contain LIKE wildcards, so the prefix needs no escaping
+		where += " AND (g.moves_uci = ? OR g.moves_uci LIKE ?)"
+		args = append(args, filter.OpeningPrefix, filter.OpeningPrefix+" %")
+	}
+	if filter.ECO != "" {
+		// a prefix, so "B9" finds B90-B99
+		where += " AND g.eco LIKE ?"
+		args = append(args, filter.ECO+"%")
+	}
+	if filter.MinPlies > 0 {
+		where += " AND g.ply_count >= ?"
+		args = append(args, filter.MinPlies)
+	}
+	if filter.MaxPlies > 0 {
+		where += " AND g.ply_count <= ?"
+		args = append(args, filter.MaxPlies)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND g.played_at >= ?"
+		args = append(args, formatPlayedAt(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where += " AND g.played_at < ?"
+		args = append(args, formatPlayedAt(filter.Until))
 	}
 
 	countQuery := "SELECT COUNT(*) FROM games g " + where
@@ -146,22 +305,30 @@ func (s *Store) SearchGames(ctx context.Context, filter GameSearchFilter, limit
 		return 0, nil, err
 	}
 
+	orderBy, ok := searchOrders[filter.Order]
+	if !ok {
+		orderBy = searchOrders["id"]
+	}
 	listQuery := `
 		SELECT g.id,
 			g.played_at,
 			w.name AS white,
 			b.name AS black,
 			g.movetime_ms,
+			g.search_limit,
+			g.seed,
 			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
 			g.termination AS termination,
 			g.moves_uci,
 			g.ply_count,
-			g.book_plies
+			g.book_plies,
+			g.eco,
+			g.opening
 		FROM games g
 		LEFT JOIN players w ON g.white_player_id = w.id
 		LEFT JOIN players b ON g.black_player_id = b.id
 		` + where + `
-		ORDER BY g.id DESC
+		ORDER BY ` + orderBy + `
 		LIMIT ?
 	`
 	listArgs := append(args, limit)
@@ -172,6 +339,11 @@ func (s *Store) SearchGames(ctx context.Context, filter GameSearchFilter, limit
 	return total, results, nil
 }
 
+// formatPlayedAt matches the played_at column default, so bounds compare as strings.
+func formatPlayedAt(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
 func (s *Store) ListResults(ctx context.Context) ([]string, error) {
 	var raw []string
 	err := s.db.SelectContext(ctx, &raw, `
@@ -191,6 +363,25 @@ func (s *Store) ListResults(ctx context.Context) ([]string, error) {
 	return out, nil
 }
 
+func (s *Store) ListSearchLimits(ctx context.Context) ([]string, error) {
+	var raw []string
+	err := s.db.SelectContext(ctx, &raw, `
+		SELECT DISTINCT search_limit
+		FROM games
+		ORDER BY 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, limit := range raw {
+		if limit != "" {
+			out = append(out, limit)
+		}
+	}
+	return out, nil
+}
+
 func (s *Store) ListTerminations(ctx context.Context) ([]string, error) {
 	var raw []string
 	err := s.db.SelectContext(ctx, &raw, `
@@ -287,13 +478,13 @@ func (s *Store) ResultsByPair(ctx context.Context) ([]PairResult, error) {
 
 func (s *Store) ListMatchupSummaries(ctx context.Context) ([]MatchupSummary, error) {
 	type summaryRow struct {
-		WhiteID  int64  `db:"white_player_id"`
-		BlackID  int64  `db:"black_player_id"`
-		White    string `db:"white"`
-		Black    string `db:"black"`
-		Movetime int    `db:"movetime_ms"`
-		Result   string `db:"result"`
-		Count    int    `db:"count"`
+		WhiteID     int64  `db:"white_player_id"`
+		BlackID     int64  `db:"black_player_id"`
+		White       string `db:"white"`
+		Black       string `db:"black"`
+		SearchLimit string `db:"search_limit"`
+		Result      string `db:"result"`
+		Count       int    `db:"count"`
 	}
 	var rows []summaryRow
 	if err := s.db.SelectContext(ctx, &rows, `
@@ -301,24 +492,29 @@ func (s *Store) ListMatchupSummaries(ctx context.Context) ([]MatchupSummary, err
 			g.black_player_id,
 			w.name AS white,
 			b.name AS black,
-				g.movetime_ms,
+			g.search_limit,
 			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
 			COUNT(*) AS count
 		FROM games g
 		LEFT JOIN players w ON g.white_player_id = w.id
 		LEFT JOIN players b ON g.black_player_id = b.id
-			GROUP BY g.white_player_id, g.black_player_id, g.movetime_ms, result
+		GROUP BY g.white_player_id, g.black_player_id, g.search_limit, result
 	`); err != nil {
 		return nil, err
 	}
 
-	counts := make(map[[3]int64]*MatchupSummary)
+	type summaryKey struct {
+		AID         int64
+		BID         int64
+		SearchLimit string
+	}
+	counts := make(map[summaryKey]*MatchupSummary)
 	for _, row := range rows {
 		whiteID := row.WhiteID
 		blackID := row.BlackID
 		white := row.White
 		black := row.Black
-		movetime := row.Movetime
+		searchLimit := row.SearchLimit
 		result := row.Result
 		count := row.Count
 		if result != "1-0" && result != "0-1" && result != "1/2-1/2" {
@@ -332,10 +528,10 @@ func (s *Store) ListMatchupSummaries(ctx context.Context) ([]MatchupSummary, err
 			aID, bID = bID, aID
 			swap = true
 		}
-		key := [3]int64{aID, bID, int64(movetime)}
+		key := summaryKey{AID: aID, BID: bID, SearchLimit: searchLimit}
 		entry, ok := counts[key]
 		if !ok {
-			entry = &MatchupSummary{A: a, B: b, AID: aID, BID: bID, MovetimeMS: movetime}
+			entry = &MatchupSummary{A: a, B: b, AID: aID, BID: bID, SearchLimit: searchLimit}
 			counts[key] = entry
 		}
 		switch result {
@@ -363,6 +559,58 @@ func (s *Store) ListMatchupSummaries(ctx context.Context) ([]MatchupSummary, err
 	return results, nil
 }
 
+// MatchupSummariesByColor is ListMatchupSummaries without merging the two
+// colors: each summary is one ordered pair with A as White and B as Black,
+// so WinsA counts White's wins.
+func (s *Store) MatchupSummariesByColor(ctx context.Context) ([]MatchupSummary, error) {
+	type colorRow struct {
+		WhiteID     int64  `db:"white_player_id"`
+		BlackID     int64  `db:"black_player_id"`
+		White       string `db:"white"`
+		Black       string `db:"black"`
+		SearchLimit string `db:"search_limit"`
+		WhiteWins   int    `db:"white_wins"`
+		BlackWins   int    `db:"black_wins"`
+		Draws       int    `db:"draws"`
+	}
+	var rows []colorRow
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT g.white_player_id,
+			g.black_player_id,
+			COALESCE(w.name, '') AS white,
+			COALESCE(b.name, '') AS black,
+			g.search_limit,
+			SUM(CASE WHEN g.result = '1-0' THEN 1 ELSE 0 END) AS white_wins,
+			SUM(CASE WHEN g.result = '0-1' THEN 1 ELSE 0 END) AS black_wins,
+			SUM(CASE WHEN g.result = '1/2-1/2' THEN 1 ELSE 0 END) AS draws
+		FROM games g
+		LEFT JOIN players w ON g.white_player_id = w.id
+		LEFT JOIN players b ON g.black_player_id = b.id
+		WHERE g.result IN ('1-0', '0-1', '1/2-1/2')
+		GROUP BY g.white_player_id, g.black_player_id, g.search_limit
+	`); err != nil {
+		return nil, err
+	}
+
+	results := make([]MatchupSummary, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, MatchupSummary{
+			AID:         row.WhiteID,
+			BID:         row.BlackID,
+			A:           row.White,
+			B:           row.Black,
+			SearchLimit: row.SearchLimit,
+			WinsA:       row.WhiteWins,
+			WinsB:       row.BlackWins,
+			Draws:       row.Draws,
+		})
+	}
+	return results, nil
+}
+
+// ListMatchupCounts returns one row per ordered (white, black) pair. A
+// self-play pair (A, A) is a single row counting each game once. Aborted
+// games don't count, so the scheduler plays them again.
 func (s *Store) ListMatchupCounts(ctx context.Context) ([]MatchupCount, error) {
 	var out []MatchupCount
 	err := s.db.SelectContext(ctx, &out, `
@@ -370,8 +618,9 @@ func (s *Store) ListMatchupCounts(ctx context.Context) ([]MatchupCount, error) {
 			g.black_player_id AS black_id,
 			COUNT(*) AS count
 		FROM games g
+		WHERE g.termination <> ?
 		GROUP BY g.white_player_id, g.black_player_id
-	`)
+	`, TerminationAborted)
 	return out, err
 }
 
@@ -387,76 +636,44 @@ func (s *Store) ListResultSummaries(ctx context.Context) ([]ResultSummary, error
 	return out, err
 }
 
-// MatchupMovesLines returns one line per game for a specific matchup and movetime.
-func (s *Store) MatchupMovesLines(ctx context.Context, a, b int64, movetimeMS int) (string, error) {
-	var rows []GameMovesRow
-	if err := s.db.SelectContext(ctx, &rows, `
-		SELECT moves_uci,
-			CASE WHEN result = '' THEN '*' ELSE result END AS result
+// WriteMatchupMovesLines streams one line per game for a specific matchup and
+// search limit.
+func (s *Store) WriteMatchupMovesLines(ctx context.Context, w io.Writer, a, b int64, searchLimit string) error {
+	return s.writeMovesLines(ctx, w, `
+		SELECT moves_uci, result
 		FROM games
-		WHERE movetime_ms = ?
+		WHERE search_limit = ?
 		  AND ((white_player_id = ? AND black_player_id = ?) OR (white_player_id = ? AND black_player_id = ?))
 		ORDER BY id ASC
-	`, movetimeMS, a, b, b, a); err != nil {
-		return "", err
-	}
-
-	out := ""
-	for _, row := range rows {
-		result := row.Result
-		if result == "" {
-			result = "*"
-		}
-		if row.MovesUCI != "" {
-			out += row.MovesUCI + " " + result + "\n"
-		} else {
-			out += result + "\n"
-		}
-	}
-	return out, nil
+	`, searchLimit, a, b, b, a)
 }
 
-// ResultMovesLines returns one line per game for a specific result/termination.
-func (s *Store) ResultMovesLines(ctx context.Context, result, termination string) (string, error) {
-	var rows []GameMovesRow
-	if err := s.db.SelectContext(ctx, &rows, `
-		SELECT moves_uci,
-			CASE WHEN result = '' THEN '*' ELSE result END AS result
+// WriteResultMovesLines streams one line per game for a specific
+// result/termination.
+func (s *Store) WriteResultMovesLines(ctx context.Context, w io.Writer, result, termination string) error {
+	return s.writeMovesLines(ctx, w, `
+		SELECT moves_uci, result
 		FROM games
 		WHERE (CASE WHEN result = '' THEN '*' ELSE result END) = ? AND termination = ?
 		ORDER BY id ASC
-	`, result, termination); err != nil {
-		return "", err
-	}
-
-	out := ""
-	for _, row := range rows {
-		lineResult := row.Result
-		if lineResult == "" {
-			lineResult = "*"
-		}
-		if row.MovesUCI != "" {
-			out += row.MovesUCI + " " + lineResult + "\n"
-		} else {
-			out += lineResult + "\n"
-		}
-	}
-	return out, nil
+	`, result, termination)
 }
 
-func (s *Store) DeleteMatchupGames(ctx context.Context, a, b int64, movetimeMS int) (int64, error) {
+func (s *Store) DeleteMatchupGames(ctx context.Context, a, b int64, searchLimit string) (int64, error) {
 	res, err := s.db.ExecContext(ctx, `
 		DELETE FROM games
-		WHERE movetime_ms = ?
+		WHERE search_limit = ?
 		  AND ((white_player_id = ? AND black_player_id = ?) OR (white_player_id = ? AND black_player_id = ?))
-	`, movetimeMS, a, b, b, a)
+	`, searchLimit, a, b, b, a)
 	if err != nil {
 		return 0, err
 	}
 	rows, err := res.RowsAffected()
 	if err != nil {
+		s.gameCount.invalidate()
 		return 0, err
 	}
+	s.gameCount.add(-int(rows))
 	return rows, nil
 }
 
@@ -470,36 +687,65 @@ func (s *Store) DeleteResultGames(ctx context.Context, result, termination strin
 	}
 	rows, err := res.RowsAffected()
 	if err != nil {
+		s.gameCount.invalidate()
 		return 0, err
 	}
+	s.gameCount.add(-int(rows))
 	return rows, nil
 }
 
-// AllFinishedMovesLines returns one line per game: "<moves> <result>".
-func (s *Store) AllFinishedMovesLines(ctx context.Context) (string, error) {
-	var rows []GameMovesRow
-	if err := s.db.SelectContext(ctx, &rows, `
-		SELECT moves_uci,
-			CASE WHEN result = '' THEN '*' ELSE result END AS result
+// WriteAllMovesLines streams one line per game: "<moves> <result>".
+func (s *Store) WriteAllMovesLines(ctx context.Context, w io.Writer) error {
+	return s.writeMovesLines(ctx, w, `
+		SELECT moves_uci, result
 		FROM games
 		ORDER BY id ASC
-	`); err != nil {
-		return "", err
+	`)
+}
+
+// WriteEngineMovesLines streams one line per game the engine played, as
+// either color.
+func (s *Store) WriteEngineMovesLines(ctx context.Context, w io.Writer, engineID int64) error {
+	return s.writeMovesLines(ctx, w, `
+		SELECT moves_uci, result
+		FROM games
+		WHERE white_player_id = ? OR black_player_id = ?
+		ORDER BY id ASC
+	`, engineID, engineID)
+}
+
+// writeMovesLines runs query and writes each row as it is read, so exports
+// don't hold the whole table in memory.
+func (s *Store) writeMovesLines(ctx context.Context, w io.Writer, query string, args ...any) error {
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	out := ""
-	for _, row := range rows {
+	bw := bufio.NewWriter(w)
+	for rows.Next() {
+		var row GameMovesRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
 		result := row.Result
 		if result == "" {
 			result = "*"
 		}
 		if row.MovesUCI != "" {
-			out += row.MovesUCI + " " + result + "\n"
-		} else {
-			out += result + "\n"
+			bw.WriteString(row.MovesUCI)
+			bw.WriteByte(' ')
+		}
+		bw.WriteString(result)
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
 		}
 	}
-	return out, nil
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
 }
 
 func (s *Store) CountGames(ctx context.Context) (int, error) {
@@ -507,3 +753,97 @@ func (s *Store) CountGames(ctx context.Context) (int, error) {
 	err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM games`)
 	return count, err
 }
+
+type BookPlyCount struct {
+	BookPlies int `db:"book_plies"`
+	Count     int `db:"count"`
+}
+
+// BookPlyHistogram counts finished games by the ply they left the book at,
+// ordered by ply.
+func (s *Store) BookPlyHistogram(ctx context.Context) ([]BookPlyCount, error) {
+	var out []BookPlyCount
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT book_plies, COUNT(*) AS count
+		FROM games
+		WHERE result IN ('1-0', '0-1', '1/2-1/2')
+		GROUP BY book_plies
+		ORDER BY book_plies
+	`)
+	return out, err
+}
+
+// positionScanMaxGames bounds GamesContainingPosition; positions aren't
+// indexed, so every scanned game is replayed.
+const positionScanMaxGames = 20000
+
+// PositionMatch is a game that reached a position, and the ply it first
+// did so at (0 for the starting position).
+type PositionMatch struct {
+	GameDetail
+	Ply int
+}
+
+// GamesContainingPosition replays the newest games (up to
+// positionScanMaxGames) and returns at most limit of them that passed
+// through the position with the given polyglot key, newest first.
+func (s *Store) GamesContainingPosition(ctx context.Context, zobrist uint64, limit int) ([]PositionMatch, error) {
+	rows, err := s.db.QueryxContext(ctx, `
+		SELECT g.id,
+			g.played_at,
+			w.name AS white,
+			b.name AS black,
+			g.movetime_ms,
+			g.search_limit,
+			g.seed,
+			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
+			g.termination AS termination,
+			g.moves_uci,
+			g.ply_count,
+			g.book_plies,
+			g.eco,
+			g.opening
+		FROM games g
+		LEFT JOIN players w ON g.white_player_id = w.id
+		LEFT JOIN players b ON g.black_player_id = b.id
+		ORDER BY g.id DESC
+		LIMIT ?
+	`, positionScanMaxGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PositionMatch
+	for rows.Next() && len(out) < limit {
+		var g GameDetail
+		if err := rows.StructScan(&g); err != nil {
+			return nil, err
+		}
+		if ply := firstPlyWithKey(g.MovesUCI, zobrist); ply >= 0 {
+			out = append(out, PositionMatch{GameDetail: g, Ply: ply})
+		}
+	}
+	return out, rows.Err()
+}
+
+// firstPlyWithKey replays a game from the start and returns the first ply
+// whose position has the given key, or -1.
+func firstPlyWithKey(movesUCI string, zobrist uint64) int {
+	pos := chess.StartingPosition()
+	if book.ZobristKey(pos) == zobrist {
+		return 0
+	}
+	notation := chess.UCINotation{}
+	for i, move := range strings.Fields(movesUCI) {
+		mv, err := notation.Decode(pos, move)
+		if err != nil {
+			return -1
+		}
+		pos = pos.Update(mv)
+		if book.ZobristKey(pos) == zobrist {
+			return i + 1
+		}
+	}
+	return -1
+}
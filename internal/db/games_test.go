@@ -0,0 +1,396 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+
+	"tethys/internal/book"
+)
+
+func TestListMatchupCounts(t *testing.T) {
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, a, b, "0-1")
+	insertTestGame(t, s, b, a, "1/2-1/2")
+	insertTestGame(t, s, a, a, "1-0")
+	insertTestGame(t, s, a, a, "0-1")
+	insertTestGame(t, s, a, a, "1/2-1/2")
+	if _, err := s.InsertFinishedGame(context.Background(), a, b, 100, "movetime:100", 0, "", "", TerminationAborted, "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.ListMatchupCounts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[[2]int64]int, len(rows))
+	for _, row := range rows {
+		key := [2]int64{row.WhiteID, row.BlackID}
+		if _, dup := got[key]; dup {
+			t.Errorf("duplicate row for %v", key)
+		}
+		got[key] = row.Count
+	}
+	want := map[[2]int64]int{
+		{a, b}: 2,
+		{b, a}: 1,
+		{a, a}: 3,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d rows, want %d: %v", len(got), len(want), got)
+	}
+	for key, n := range want {
+		if got[key] != n {
+			t.Errorf("%v: got %d games, want %d", key, got[key], n)
+		}
+	}
+}
+
+func TestMatchupSummariesByColor(t *testing.T) {
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, a, b, "1/2-1/2")
+	insertTestGame(t, s, b, a, "0-1")
+	insertTestGame(t, s, b, a, "")
+
+	rows, err := s.MatchupSummariesByColor(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[[2]int64][3]int, len(rows))
+	for _, row := range rows {
+		got[[2]int64{row.AID, row.BID}] = [3]int{row.WinsA, row.Draws, row.WinsB}
+	}
+	want := map[[2]int64][3]int{
+		{a, b}: {2, 1, 0},
+		{b, a}: {0, 0, 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("white/draw/black by pair = %v, want %v", got, want)
+	}
+}
+
+func TestBookPlyHistogram(t *testing.T) {
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	for _, g := range []struct {
+		result    string
+		bookPlies int
+	}{{"1-0", 4}, {"0-1", 4}, {"1/2-1/2", 0}, {"1-0", 8}, {"", 4}} {
+		if _, err := s.InsertFinishedGame(context.Background(), a, b, 100, "movetime:100", 0, "", g.result, "", "e2e4", g.bookPlies); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.BookPlyHistogram(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BookPlyCount{{0, 1}, {4, 2}, {8, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("BookPlyHistogram = %v, want %v", got, want)
+	}
+}
+
+func TestGamesContainingPosition(t *testing.T) {
+	s := openTestStore(t)
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	insert := func(moves string) int64 {
+		id, err := s.InsertFinishedGame(context.Background(), a, b, 100, "movetime:100", 0, "", "1-0", "", moves, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	e4e5 := insert("e2e4 e7e5 g1f3")
+	insert("d2d4 d7d5")
+	transposed := insert("g1f3 e7e5 e2e4")
+
+	pos := chess.StartingPosition()
+	for _, move := range []string{"e2e4", "e7e5"} {
+		mv, err := chess.UCINotation{}.Decode(pos, move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos = pos.Update(mv)
+	}
+	matches, err := s.GamesContainingPosition(context.Background(), book.ZobristKey(pos), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ID != e4e5 || matches[0].Ply != 2 {
+		t.Fatalf("after 1.e4 e5: got %+v, want game %d at ply 2", matches, e4e5)
+	}
+
+	// 1.e4 e5 2.Nf3 and 1.Nf3 e5 2.e4 reach the same position
+	mv, _ := chess.UCINotation{}.Decode(pos, "g1f3")
+	matches, err = s.GamesContainingPosition(context.Background(), book.ZobristKey(pos.Update(mv)), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ID != transposed || matches[0].Ply != 3 {
+		t.Fatalf("limit 1 after transposition: got %+v, want game %d at ply 3", matches, transposed)
+	}
+}
+
+func TestSearchGamesOrder(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	games := []struct {
+		moves    string
+		playedAt string
+	}{
+		{"e2e4 e7e5", "2024-03-01 10:00:00"},
+		{"d2d4", "2024-01-01 10:00:00"},
+		{"c2c4 e7e5 b1c3", "2024-02-01 10:00:00"},
+	}
+	ids := make([]int64, len(games))
+	for i, g := range games {
+		id, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "1-0", "", g.moves, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.db.MustExec(`UPDATE games SET played_at = ? WHERE id = ?`, g.playedAt, id)
+		ids[i] = id
+	}
+
+	for _, tc := range []struct {
+		order string
+		want  []int64
+	}{
+		{"", []int64{ids[2], ids[1], ids[0]}},
+		{"id", []int64{ids[2], ids[1], ids[0]}},
+		{"date", []int64{ids[0], ids[2], ids[1]}},
+		{"plies", []int64{ids[2], ids[0], ids[1]}},
+		{"id; DROP TABLE games", []int64{ids[2], ids[1], ids[0]}},
+	} {
+		_, rows, err := s.SearchGames(ctx, GameSearchFilter{Order: tc.order}, 10)
+		if err != nil {
+			t.Fatalf("order %q: %v", tc.order, err)
+		}
+		got := make([]int64, len(rows))
+		for i, row := range rows {
+			got[i] = row.ID
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("order %q: got %v, want %v", tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestSearchGamesStatus(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	insertTestGame(t, s, a, b, "1-0")
+	if _, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "", TerminationAborted, "e2e4", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "", TerminationEngineCrash, "e2e4 e7e5", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		filter GameSearchFilter
+		want   int
+	}{
+		{GameSearchFilter{}, 1},
+		{GameSearchFilter{IncludeUnfinished: true}, 3},
+		{GameSearchFilter{Result: "*"}, 2},
+		{GameSearchFilter{Status: GameStatusFinished}, 1},
+		{GameSearchFilter{Status: GameStatusAborted}, 1},
+		{GameSearchFilter{Status: GameStatusUnfinished}, 1},
+		{GameSearchFilter{Status: GameStatusUnfinished, Termination: TerminationAborted}, 0},
+	} {
+		total, rows, err := s.SearchGames(ctx, tc.filter, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != tc.want || len(rows) != tc.want {
+			t.Errorf("%+v: %d games (%d rows), want %d", tc.filter, total, len(rows), tc.want)
+		}
+	}
+}
+
+func TestWriteEnginePGN(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	c := insertTestEngine(t, s, "c")
+	if _, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "0-1", "Checkmate", "f2f3 e7e5 g2g4 d8h4", 0); err != nil {
+		t.Fatal(err)
+	}
+	insertTestGame(t, s, b, c, "1-0")
+
+	var buf bytes.Buffer
+	if err := s.WriteEnginePGN(ctx, &buf, a); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`[White "a"]`,
+		`[Black "b"]`,
+		`[Result "0-1"]`,
+		`[Termination "Checkmate"]`,
+		"1. f3 e5 2. g4 Qh4# 0-1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PGN missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "[Event "); n != 1 {
+		t.Errorf("got %d games, want 1:\n%s", n, out)
+	}
+}
+
+func TestWriteResultPGN(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	if _, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", "0-1", "Time forfeit", "e2e4 e7e5", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.InsertFinishedGame(ctx, b, a, 100, "movetime:100", 0, "", "0-1", "Checkmate", "f2f3 e7e5 g2g4 d8h4", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteResultPGN(ctx, &buf, "0-1", "Time forfeit"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`[White "a"]`, `[Black "b"]`, `[Termination "Time forfeit"]`, "1. e4 e5 0-1\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PGN missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "[Event "); n != 1 {
+		t.Errorf("got %d games, want 1:\n%s", n, out)
+	}
+}
+
+func TestCachedGameCount(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	insertTestGame(t, s, a, b, "1-0")
+
+	check := func(want int) {
+		t.Helper()
+		got, err := s.CachedGameCount(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("CachedGameCount = %d, want %d", got, want)
+		}
+	}
+	check(1)
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, b, a, "0-1")
+	check(3)
+	if _, err := s.DeleteResultGames(ctx, "1-0", "Checkmate"); err != nil {
+		t.Fatal(err)
+	}
+	check(1)
+	if _, err := s.DeleteGamesByEngine(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	check(0)
+}
+
+func TestGameHighlights(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	hl, err := s.GameHighlights(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hl.ShortestDecisive != nil || hl.Longest != nil || hl.LatestDecisive != nil {
+		t.Fatalf("highlights without games: %+v", hl)
+	}
+
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	games := []struct{ result, moves string }{
+		{"0-1", "f2f3 e7e5 g2g4 d8h4"},
+		{"1/2-1/2", "e2e4 e7e5 g1f3 b8c6 f1c4 g8f6"},
+		{"1-0", "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6 h5f7"},
+		{"1/2-1/2", "d2d4"},
+	}
+	ids := make([]int64, len(games))
+	for i, g := range games {
+		id, err := s.InsertFinishedGame(ctx, a, b, 100, "movetime:100", 0, "", g.result, "", g.moves, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	hl, err = s.GameHighlights(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		got  *GameDetail
+		want int64
+	}{
+		{"shortest decisive", hl.ShortestDecisive, ids[0]},
+		{"longest", hl.Longest, ids[2]},
+		{"latest decisive", hl.LatestDecisive, ids[2]},
+	} {
+		if c.got == nil || c.got.ID != c.want {
+			t.Errorf("%s = %+v, want game %d", c.name, c.got, c.want)
+		}
+	}
+	if hl.Longest != nil && hl.Longest.Plies != 7 {
+		t.Errorf("longest game has %d plies, want 7", hl.Longest.Plies)
+	}
+}
+
+func TestRecentGamesForEngine(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	c := insertTestEngine(t, s, "c")
+	insertTestGame(t, s, a, b, "1-0")
+	insertTestGame(t, s, b, c, "0-1")
+	insertTestGame(t, s, c, a, "1/2-1/2")
+
+	games, err := s.RecentGamesForEngine(ctx, a, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].White != "c" || games[1].White != "a" {
+		t.Fatalf("games of a = %+v", games)
+	}
+	games, err = s.RecentGamesForEngine(ctx, b, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || games[0].Black != "c" {
+		t.Fatalf("latest game of b = %+v", games)
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// pgnLineWidth is where movetext wraps; the PGN spec caps lines at 255, 80
+// keeps files readable.
+const pgnLineWidth = 80
+
+// WriteEnginePGN streams every game the engine played, as either color, as
+// PGN.
+func (s *Store) WriteEnginePGN(ctx context.Context, w io.Writer, engineID int64) error {
+	return s.writePGNGames(ctx, w, `g.white_player_id = ? OR g.black_player_id = ?`, engineID, engineID)
+}
+
+// WriteResultPGN streams the games of one result bucket as PGN; see
+// WriteResultMovesLines.
+func (s *Store) WriteResultPGN(ctx context.Context, w io.Writer, result, termination string) error {
+	return s.writePGNGames(ctx, w, `(CASE WHEN g.result = '' THEN '*' ELSE g.result END) = ? AND g.termination = ?`, result, termination)
+}
+
+// writePGNGames streams the games matching where, oldest first, as PGN.
+func (s *Store) writePGNGames(ctx context.Context, w io.Writer, where string, args ...any) error {
+	rows, err := s.db.QueryxContext(ctx, `
+		SELECT g.id,
+			g.played_at,
+			COALESCE(w.name, '') AS white,
+			COALESCE(b.name, '') AS black,
+			CASE WHEN g.result = '' THEN '*' ELSE g.result END AS result,
+			g.termination,
+			g.moves_uci,
+			g.eco,
+			g.opening
+		FROM games g
+		LEFT JOIN players w ON g.white_player_id = w.id
+		LEFT JOIN players b ON g.black_player_id = b.id
+		WHERE `+where+`
+		ORDER BY g.id ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	for rows.Next() {
+		var g GameDetail
+		if err := rows.StructScan(&g); err != nil {
+			return err
+		}
+		if err := writePGN(bw, g); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writePGN writes one game as PGN, converting the stored UCI moves to SAN.
+// A move that no longer replays (a corrupt row) ends the movetext with a
+// comment instead of failing the whole export.
+func writePGN(w *bufio.Writer, g GameDetail) error {
+	date := "????.??.??"
+	if len(g.PlayedAt) >= 10 {
+		date = strings.ReplaceAll(g.PlayedAt[:10], "-", ".")
+	}
+	tags := [][2]string{
+		{"Event", "tethys"},
+		{"Site", "?"},
+		{"Date", date},
+		{"Round", fmt.Sprint(g.ID)},
+		{"White", g.White},
+		{"Black", g.Black},
+		{"Result", g.Result},
+	}
+	if g.Termination != "" {
+		tags = append(tags, [2]string{"Termination", g.Termination})
+	}
+	if g.ECO != "" {
+		tags = append(tags, [2]string{"ECO", g.ECO}, [2]string{"Opening", g.Opening})
+	}
+	for _, tag := range tags {
+		fmt.Fprintf(w, "[%s \"%s\"]\n", tag[0], pgnEscape(tag[1]))
+	}
+	w.WriteByte('\n')
+
+	tokens := pgnMovetext(g.MovesUCI)
+	tokens = append(tokens, g.Result)
+	width := 0
+	for _, tok := range tokens {
+		if width > 0 && width+1+len(tok) > pgnLineWidth {
+			w.WriteByte('\n')
+			width = 0
+		} else if width > 0 {
+			w.WriteByte(' ')
+			width++
+		}
+		w.WriteString(tok)
+		width += len(tok)
+	}
+	_, err := w.WriteString("\n\n")
+	return err
+}
+
+// pgnMovetext returns the SAN tokens, move numbers included, for a
+// space-separated UCI move list played from the start position.
+func pgnMovetext(movesUCI string) []string {
+	var tokens []string
+	pos := chess.StartingPosition()
+	uci := chess.UCINotation{}
+	san := chess.AlgebraicNotation{}
+	for i, raw := range strings.Fields(movesUCI) {
+		mv := legalMove(pos, uci, raw)
+		if mv == nil {
+			tokens = append(tokens, fmt.Sprintf("{invalid move %s}", raw))
+			break
+		}
+		if i%2 == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d.", i/2+1))
+		}
+		tokens = append(tokens, san.Encode(pos, mv))
+		pos = pos.Update(mv)
+	}
+	return tokens
+}
+
+// legalMove returns the legal move matching raw. Decoded UCI moves lack the
+// check tags SAN needs for "+" and "#", the generated ones carry them.
+func legalMove(pos *chess.Position, uci chess.UCINotation, raw string) *chess.Move {
+	for _, mv := range pos.ValidMoves() {
+		if uci.Encode(pos, mv) == raw {
+			return mv
+		}
+	}
+	return nil
+}
+
+func pgnEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
@@ -5,6 +5,10 @@ import (
 	"database/sql"
 )
 
+// QuickMatchPriority marks games queued by hand. DequeueGame hands out
+// higher priorities first, so they run before the scheduler's own entries.
+const QuickMatchPriority = 1
+
 func (s *Store) EnqueueGames(ctx context.Context, entries []GameQueueEntry) error {
 	if len(entries) == 0 {
 		return nil
@@ -20,8 +24,8 @@ func (s *Store) EnqueueGames(ctx context.Context, entries []GameQueueEntry) erro
 	}()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO game_queue (white_player_id, black_player_id, movetime_ms, book_path)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO game_queue (white_player_id, black_player_id, movetime_ms, book_path, search_limit, priority)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -29,7 +33,7 @@ func (s *Store) EnqueueGames(ctx context.Context, entries []GameQueueEntry) erro
 	defer stmt.Close()
 
 	for _, entry := range entries {
-		if _, err = stmt.ExecContext(ctx, entry.WhiteID, entry.BlackID, entry.MovetimeMS, entry.BookPath); err != nil {
+		if _, err = stmt.ExecContext(ctx, entry.WhiteID, entry.BlackID, entry.MovetimeMS, entry.BookPath, entry.SearchLimit, entry.Priority); err != nil {
 			return err
 		}
 	}
@@ -50,9 +54,9 @@ func (s *Store) DequeueGame(ctx context.Context) (GameQueueEntry, bool, error) {
 
 	var entry GameQueueEntry
 	if err = tx.GetContext(ctx, &entry, `
-		SELECT id, created_at, white_player_id, black_player_id, movetime_ms, book_path
+		SELECT id, created_at, white_player_id, black_player_id, movetime_ms, book_path, search_limit, priority
 		FROM game_queue
-		ORDER BY id ASC
+		ORDER BY priority DESC, id ASC
 		LIMIT 1
 	`); err != nil {
 		if err == sql.ErrNoRows {
@@ -72,11 +76,47 @@ func (s *Store) DequeueGame(ctx context.Context) (GameQueueEntry, bool, error) {
 	return entry, true, nil
 }
 
+// PruneGameQueue deletes queued games whose engines no longer exist or have
+// no path, as after hand-editing the database with foreign keys off.
+func (s *Store) PruneGameQueue(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM game_queue
+		WHERE white_player_id NOT IN (SELECT id FROM players WHERE name != '' AND engine_path != '')
+		   OR black_player_id NOT IN (SELECT id FROM players WHERE name != '' AND engine_path != '')
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ClearGameQueue deletes every queued game, quick matches included.
 func (s *Store) ClearGameQueue(ctx context.Context) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM game_queue`)
 	return err
 }
 
+// ClearScheduledGames deletes the games the scheduler queued, so that the
+// next refill rebalances, and keeps quick matches. It returns how many were
+// deleted.
+func (s *Store) ClearScheduledGames(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM game_queue WHERE priority = 0`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteQueuedGamesByEngine deletes the queued games engine id plays in,
+// quick matches included.
+func (s *Store) DeleteQueuedGamesByEngine(ctx context.Context, id int64) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM game_queue WHERE white_player_id = ? OR black_player_id = ?`, id, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *Store) GameQueueSize(ctx context.Context) (int, error) {
 	var count int
 	err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM game_queue`)
@@ -96,11 +136,13 @@ func (s *Store) ListGameQueue(ctx context.Context, limit int) ([]GameQueueRow, e
 			w.name AS white,
 			b.name AS black,
 			q.movetime_ms,
-			q.book_path
+			q.book_path,
+			q.search_limit,
+			q.priority
 		FROM game_queue q
 		LEFT JOIN players w ON q.white_player_id = w.id
 		LEFT JOIN players b ON q.black_player_id = b.id
-		ORDER BY q.id ASC
+		ORDER BY q.priority DESC, q.id ASC
 		LIMIT ?
 	`, limit)
 	return rows, err
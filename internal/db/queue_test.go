@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPruneGameQueue(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a, err := s.InsertEngine(ctx, Engine{Name: "a", Path: "/bin/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.InsertEngine(ctx, Engine{Name: "b", Path: "/bin/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noPath := insertTestEngine(t, s, "no-path")
+	if err := s.EnqueueGames(ctx, []GameQueueEntry{
+		{WhiteID: a, BlackID: b},
+		{WhiteID: b, BlackID: noPath},
+		{WhiteID: b, BlackID: a},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// a hand edit with foreign keys off can leave entries for deleted engines
+	s.db.MustExec(`PRAGMA foreign_keys=OFF`)
+	s.db.MustExec(`INSERT INTO game_queue (white_player_id, black_player_id) VALUES (?, 999)`, a)
+	s.db.MustExec(`PRAGMA foreign_keys=ON`)
+
+	n, err := s.PruneGameQueue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("pruned %d entries, want 2", n)
+	}
+	size, err := s.GameQueueSize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 2 {
+		t.Errorf("queue size = %d, want 2", size)
+	}
+}
+
+func TestDequeueGamePriority(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	if err := s.EnqueueGames(ctx, []GameQueueEntry{
+		{WhiteID: a, BlackID: b},
+		{WhiteID: b, BlackID: a, Priority: QuickMatchPriority},
+		{WhiteID: a, BlackID: a},
+		{WhiteID: b, BlackID: b, Priority: QuickMatchPriority},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]int64{{b, a}, {b, b}, {a, b}, {a, a}}
+	for i, w := range want {
+		entry, ok, err := s.DequeueGame(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("dequeue %d: queue empty", i)
+		}
+		if got := [2]int64{entry.WhiteID, entry.BlackID}; got != w {
+			t.Errorf("dequeue %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestClearScheduledGamesKeepsQuickMatches(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	c := insertTestEngine(t, s, "c")
+	if err := s.EnqueueGames(ctx, []GameQueueEntry{
+		{WhiteID: a, BlackID: b},
+		{WhiteID: b, BlackID: a, Priority: QuickMatchPriority},
+		{WhiteID: a, BlackID: c, Priority: QuickMatchPriority},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.ClearScheduledGames(ctx); err != nil || n != 1 {
+		t.Fatalf("cleared %d scheduled games (%v), want 1", n, err)
+	}
+	if size, err := s.GameQueueSize(ctx); err != nil || size != 2 {
+		t.Fatalf("after clearing scheduled games: %d queued (%v), want 2", size, err)
+	}
+	if n, err := s.DeleteQueuedGamesByEngine(ctx, c); err != nil || n != 1 {
+		t.Fatalf("deleted %d games of c (%v), want 1", n, err)
+	}
+	if err := s.DeleteEngine(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ClearGameQueue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.GameQueueSize(ctx); err != nil || size != 0 {
+		t.Errorf("after clearing the queue: %d queued (%v), want 0", size, err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
+
+	"tethys/internal/eco"
 )
 
 // note: as per SQLites's manual suggestions, we do not use 'AUTOINCREMENT' on
@@ -22,6 +24,7 @@ var schema_stmts = []string{
 		engine_args TEXT NOT NULL DEFAULT '',
 		engine_init TEXT NOT NULL DEFAULT '',
 		engine_elo REAL NOT NULL DEFAULT 0,
+		skip_newgame INTEGER NOT NULL DEFAULT 0,
 		UNIQUE(name)
 	);`,
 	`DROP TABLE IF EXISTS matchups;`,
@@ -36,7 +39,9 @@ var schema_stmts = []string{
 		termination TEXT NOT NULL DEFAULT '',
 		moves_uci TEXT NOT NULL DEFAULT '',
 		ply_count INTEGER NOT NULL GENERATED ALWAYS AS (length(moves_uci) - length(replace(moves_uci, ' ', '')) + CASE WHEN moves_uci = '' THEN 0 ELSE 1 END) STORED,
-		book_plies INTEGER NOT NULL DEFAULT 0
+		book_plies INTEGER NOT NULL DEFAULT 0,
+		search_limit TEXT NOT NULL DEFAULT '',
+		seed INTEGER NOT NULL DEFAULT 0
 		CHECK (result IN ('', '1-0', '0-1', '1/2-1/2'))
 		CHECK (trim(moves_uci) = moves_uci)
 	);`,
@@ -46,7 +51,8 @@ var schema_stmts = []string{
 		white_player_id INTEGER NOT NULL REFERENCES players(id) ON UPDATE CASCADE ON DELETE RESTRICT,
 		black_player_id INTEGER NOT NULL REFERENCES players(id) ON UPDATE CASCADE ON DELETE RESTRICT,
 		movetime_ms INTEGER NOT NULL DEFAULT 0,
-		book_path TEXT NOT NULL DEFAULT ''
+		book_path TEXT NOT NULL DEFAULT '',
+		search_limit TEXT NOT NULL DEFAULT ''
 	);`,
 	`CREATE TABLE IF NOT EXISTS evals (
 		zobrist_key INTEGER PRIMARY KEY,
@@ -68,6 +74,42 @@ var schema_stmts = []string{
 		key TEXT PRIMARY KEY,
 		value
 	);`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY,
+		at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+		remote TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		detail TEXT NOT NULL DEFAULT ''
+	);`,
+	`CREATE TABLE IF NOT EXISTS suite_positions (
+		id INTEGER PRIMARY KEY,
+		suite TEXT NOT NULL,
+		epd_id TEXT NOT NULL DEFAULT '',
+		fen TEXT NOT NULL,
+		zobrist_key INTEGER NOT NULL,
+		best_moves TEXT NOT NULL DEFAULT '',
+		ce INTEGER,
+		found_move TEXT NOT NULL DEFAULT '',
+		found_score TEXT NOT NULL DEFAULT '',
+		engine_id INTEGER NOT NULL DEFAULT 0,
+		depth INTEGER NOT NULL DEFAULT 0
+	);`,
+	`CREATE TABLE IF NOT EXISTS suite_runs (
+		id INTEGER PRIMARY KEY,
+		suite TEXT NOT NULL,
+		engine_id INTEGER NOT NULL REFERENCES players(id) ON UPDATE CASCADE ON DELETE CASCADE,
+		finished_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+		depth INTEGER NOT NULL DEFAULT 0,
+		solved INTEGER NOT NULL DEFAULT 0,
+		total INTEGER NOT NULL DEFAULT 0,
+		elapsed_ms INTEGER NOT NULL DEFAULT 0
+	);`,
+	`CREATE TABLE IF NOT EXISTS elo_history (
+		id INTEGER PRIMARY KEY,
+		engine_id INTEGER NOT NULL REFERENCES players(id) ON UPDATE CASCADE ON DELETE CASCADE,
+		at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+		elo REAL NOT NULL
+	);`,
 	`UPDATE players SET engine_path = '' WHERE engine_path IS NULL;`,
 	`UPDATE games SET result = '' WHERE result IS NULL;`,
 	`UPDATE games SET termination = '' WHERE termination IS NULL;`,
@@ -78,10 +120,15 @@ var schema_stmts = []string{
 	`CREATE INDEX IF NOT EXISTS idx_evals_engine_id ON evals(engine_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_engine_logs_game_ply ON engine_logs(game_id, ply);`,
 	`CREATE INDEX IF NOT EXISTS idx_game_queue_created_at ON game_queue(created_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_suite_positions_suite ON suite_positions(suite);`,
+	`CREATE INDEX IF NOT EXISTS idx_suite_runs_engine_id ON suite_runs(engine_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_elo_history_engine_id ON elo_history(engine_id);`,
 }
 
 type Store struct {
 	db *sqlx.DB
+
+	gameCount gameCounter
 }
 
 func Open(path string) (*Store, error) {
@@ -101,15 +148,67 @@ func Open(path string) (*Store, error) {
 		return nil, fmt.Errorf("ping sqlite: %w", err)
 	}
 
+	version, err := schemaVersion(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if version > len(migrations) {
+		_ = db.Close()
+		return nil, fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, len(migrations))
+	}
+
 	for _, stmt := range schema_stmts {
 		db.MustExec(stmt)
 	}
-	ensureEngineLogColumns(db)
+	migrate(db, version)
 	insertDefaultSettings(db)
 
 	return &Store{db: db}, nil
 }
 
+// migrations[i] brings the database from schema version i to i+1, where the
+// version is SQLite's user_version. Databases from before versioning are at
+// 0; the first step is the old column checks, which are safe on any of them.
+// Only ever append steps.
+var migrations = []func(db *sqlx.DB){
+	func(db *sqlx.DB) {
+		ensureEngineLogColumns(db)
+		ensurePlayerColumns(db)
+		ensureGameQueueColumns(db)
+		ensureGameColumns(db)
+		normalizeTerminations(db)
+	},
+	func(db *sqlx.DB) {
+		db.MustExec(`ALTER TABLE players ADD COLUMN strength_elo INTEGER NOT NULL DEFAULT 0`)
+	},
+	func(db *sqlx.DB) {
+		db.MustExec(`ALTER TABLE game_queue ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`)
+	},
+	func(db *sqlx.DB) {
+		db.MustExec(`ALTER TABLE engine_logs ADD COLUMN pv TEXT NOT NULL DEFAULT ''`)
+	},
+	func(db *sqlx.DB) {
+		db.MustExec(`ALTER TABLE players ADD COLUMN paused INTEGER NOT NULL DEFAULT 0`)
+	},
+}
+
+func schemaVersion(db *sqlx.DB) (int, error) {
+	var version int
+	if err := db.Get(&version, `PRAGMA user_version`); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// run the migrations after version, recording each step as it completes
+func migrate(db *sqlx.DB, version int) {
+	for i := version; i < len(migrations); i++ {
+		migrations[i](db)
+		db.MustExec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1))
+	}
+}
+
 func (s *Store) Close() error {
 	return s.db.Close()
 }
@@ -118,11 +217,31 @@ func insertDefaultSettings(db *sqlx.DB) {
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('opening_min', 20)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_engine_id', 0)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_depth', 12)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_max_jobs', 2)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_threads', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_hash_mb', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('analysis_nice', 10)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_movetime_ms', 100)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_white_movetime_ms', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_black_movetime_ms', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_search_mode', 'movetime')`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_nodes', 1000000)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_depth', 10)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_slack_ms', 100)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_fen_interval', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_store_pv', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_clear_hash', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_draw_plies', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_draw_cp', 10)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('game_book_path', '')`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('match_soft_scale', 300)`)
 	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('match_allow_mirror', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('match_seed', 0)`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('ranking_scoring', 'standard')`)
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) VALUES ('ranking_draw_score', 0.5)`)
+	// seeds used to be offset by the game count, which never exceeds the
+	// highest game id, so starting there repeats none of them
+	db.MustExec(`INSERT OR IGNORE INTO settings (key, value) SELECT 'seed_offset', COALESCE(MAX(id), 0) FROM games`)
 }
 
 func ensureEngineLogColumns(db *sqlx.DB) {
@@ -131,6 +250,65 @@ func ensureEngineLogColumns(db *sqlx.DB) {
 	}
 }
 
+func ensurePlayerColumns(db *sqlx.DB) {
+	if !tableHasColumn(db, "players", "skip_newgame") {
+		db.MustExec(`ALTER TABLE players ADD COLUMN skip_newgame INTEGER NOT NULL DEFAULT 0`)
+	}
+	if !tableHasColumn(db, "players", "notes") {
+		db.MustExec(`ALTER TABLE players ADD COLUMN notes TEXT NOT NULL DEFAULT ''`)
+	}
+	if !tableHasColumn(db, "players", "tags") {
+		db.MustExec(`ALTER TABLE players ADD COLUMN tags TEXT NOT NULL DEFAULT ''`)
+	}
+	if !tableHasColumn(db, "players", "bench_nodes") {
+		db.MustExec(`ALTER TABLE players ADD COLUMN bench_nodes INTEGER NOT NULL DEFAULT 0`)
+		db.MustExec(`ALTER TABLE players ADD COLUMN bench_nps INTEGER NOT NULL DEFAULT 0`)
+		db.MustExec(`ALTER TABLE players ADD COLUMN bench_at TEXT NOT NULL DEFAULT ''`)
+	}
+}
+
+func ensureGameQueueColumns(db *sqlx.DB) {
+	if !tableHasColumn(db, "game_queue", "search_limit") {
+		db.MustExec(`ALTER TABLE game_queue ADD COLUMN search_limit TEXT NOT NULL DEFAULT ''`)
+	}
+}
+
+func ensureGameColumns(db *sqlx.DB) {
+	if !tableHasColumn(db, "games", "search_limit") {
+		db.MustExec(`ALTER TABLE games ADD COLUMN search_limit TEXT NOT NULL DEFAULT ''`)
+		// all games before this column was added were movetime-limited
+		db.MustExec(`UPDATE games SET search_limit = 'movetime:' || movetime_ms WHERE search_limit = ''`)
+	}
+	if !tableHasColumn(db, "games", "seed") {
+		db.MustExec(`ALTER TABLE games ADD COLUMN seed INTEGER NOT NULL DEFAULT 0`)
+	}
+	if !tableHasColumn(db, "games", "eco") {
+		db.MustExec(`ALTER TABLE games ADD COLUMN eco TEXT NOT NULL DEFAULT ''`)
+		db.MustExec(`ALTER TABLE games ADD COLUMN opening TEXT NOT NULL DEFAULT ''`)
+		classifyGames(db)
+	}
+}
+
+// fill in the opening of games stored before the eco column existed
+func classifyGames(db *sqlx.DB) {
+	var rows []struct {
+		ID       int64  `db:"id"`
+		MovesUCI string `db:"moves_uci"`
+	}
+	if err := db.Select(&rows, `SELECT id, moves_uci FROM games`); err != nil {
+		panic(err)
+	}
+	tx := db.MustBegin()
+	for _, row := range rows {
+		if o, ok := eco.Classify(row.MovesUCI); ok {
+			tx.MustExec(`UPDATE games SET eco = ?, opening = ? WHERE id = ?`, o.ECO, o.Name, row.ID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		panic(err)
+	}
+}
+
 func tableHasColumn(db *sqlx.DB, table, column string) bool {
 	var cols []struct {
 		Name string `db:"name"`
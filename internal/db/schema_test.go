@@ -0,0 +1,31 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenRecordsSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := schemaVersion(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != len(migrations) {
+		t.Errorf("schema version = %d, want %d", version, len(migrations))
+	}
+
+	// a database written by a newer build is refused rather than touched
+	s.db.MustExec(`PRAGMA user_version = 1000`)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Open(path); err == nil {
+		_ = s.Close()
+		t.Fatal("Open accepted a newer schema version")
+	}
+}
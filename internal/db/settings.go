@@ -10,11 +10,24 @@ func (s *Store) GetSettings(ctx context.Context) (Settings, error) {
 		OpeningMin:       20,
 		AnalysisEngineID: 0,
 		AnalysisDepth:    12,
+		AnalysisMaxJobs:  2,
+		AnalysisNice:     10,
 		GameMovetimeMS:   100,
+		GameSearchMode:   "movetime",
+		GameNodes:        1000000,
+		GameDepth:        10,
 		GameSlackMS:      100,
+		GameFENInterval:  0,
 		GameBookPath:     "",
 		MatchSoftScale:   300,
 		MatchAllowMirror: false,
+		GameStorePV:      false,
+		GameClearHash:    false,
+		GameDrawPlies:    0,
+		GameDrawCP:       10,
+		MatchSeed:        0,
+		RankingScoring:   "standard",
+		RankingDrawScore: 0.5,
 	}
 	rows := []struct {
 		Key   string `db:"key"`
@@ -41,10 +54,44 @@ func (s *Store) GetSettings(ctx context.Context) (Settings, error) {
 			if v, err := strconv.Atoi(row.Value); err == nil {
 				settings.AnalysisDepth = v
 			}
+		case "analysis_max_jobs":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.AnalysisMaxJobs = v
+			}
+		case "analysis_threads":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.AnalysisThreads = v
+			}
+		case "analysis_hash_mb":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.AnalysisHashMB = v
+			}
+		case "analysis_nice":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.AnalysisNice = v
+			}
 		case "game_movetime_ms":
 			if v, err := strconv.Atoi(row.Value); err == nil {
 				settings.GameMovetimeMS = v
 			}
+		case "game_white_movetime_ms":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameWhiteMovetimeMS = v
+			}
+		case "game_black_movetime_ms":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameBlackMovetimeMS = v
+			}
+		case "game_search_mode":
+			settings.GameSearchMode = row.Value
+		case "game_nodes":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameNodes = v
+			}
+		case "game_depth":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameDepth = v
+			}
 		case "game_slack_ms":
 			if v, err := strconv.Atoi(row.Value); err == nil {
 				settings.GameSlackMS = v
@@ -59,6 +106,36 @@ func (s *Store) GetSettings(ctx context.Context) (Settings, error) {
 			if v, err := strconv.Atoi(row.Value); err == nil {
 				settings.MatchAllowMirror = v != 0
 			}
+		case "game_store_pv":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameStorePV = v != 0
+			}
+		case "game_clear_hash":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameClearHash = v != 0
+			}
+		case "game_draw_plies":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameDrawPlies = v
+			}
+		case "game_draw_cp":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameDrawCP = v
+			}
+		case "game_fen_interval":
+			if v, err := strconv.Atoi(row.Value); err == nil {
+				settings.GameFENInterval = v
+			}
+		case "match_seed":
+			if v, err := strconv.ParseInt(row.Value, 10, 64); err == nil {
+				settings.MatchSeed = v
+			}
+		case "ranking_scoring":
+			settings.RankingScoring = row.Value
+		case "ranking_draw_score":
+			if v, err := strconv.ParseFloat(row.Value, 64); err == nil {
+				settings.RankingDrawScore = v
+			}
 		}
 	}
 	if settings.MatchSoftScale <= 0 {
@@ -67,7 +144,90 @@ func (s *Store) GetSettings(ctx context.Context) (Settings, error) {
 	return settings, nil
 }
 
+// NextSeedOffset returns the offset of the next game's seed from a fixed
+// match seed. It goes up by one on every call and, unlike the game count,
+// never comes back down when games are deleted.
+func (s *Store) NextSeedOffset(ctx context.Context) (int64, error) {
+	var offset int64
+	err := s.db.GetContext(ctx, &offset, `
+		UPDATE settings SET value = value + 1
+		WHERE key = 'seed_offset'
+		RETURNING value - 1
+	`)
+	return offset, err
+}
+
+// UpdateSettings writes every setting.
 func (s *Store) UpdateSettings(ctx context.Context, settings Settings) error {
+	return s.writeSettings(ctx, settingValues(settings))
+}
+
+// UpdateChangedSettings writes only the settings that differ between before
+// and after. Saves from different admin pages each start from their own read,
+// so writing everything would revert whatever the other page saved meanwhile.
+func (s *Store) UpdateChangedSettings(ctx context.Context, before, after Settings) error {
+	old := settingValues(before)
+	var changed []settingValue
+	for i, v := range settingValues(after) {
+		if v.value != old[i].value {
+			changed = append(changed, v)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return s.writeSettings(ctx, changed)
+}
+
+type settingValue struct {
+	key   string
+	value any
+}
+
+// settingValues lists the settings rows in a fixed order.
+func settingValues(settings Settings) []settingValue {
+	mirror := 0
+	if settings.MatchAllowMirror {
+		mirror = 1
+	}
+	storePV := 0
+	if settings.GameStorePV {
+		storePV = 1
+	}
+	clearHash := 0
+	if settings.GameClearHash {
+		clearHash = 1
+	}
+	return []settingValue{
+		{"opening_min", settings.OpeningMin},
+		{"analysis_engine_id", settings.AnalysisEngineID},
+		{"analysis_depth", settings.AnalysisDepth},
+		{"analysis_max_jobs", settings.AnalysisMaxJobs},
+		{"analysis_threads", settings.AnalysisThreads},
+		{"analysis_hash_mb", settings.AnalysisHashMB},
+		{"analysis_nice", settings.AnalysisNice},
+		{"game_movetime_ms", settings.GameMovetimeMS},
+		{"game_white_movetime_ms", settings.GameWhiteMovetimeMS},
+		{"game_black_movetime_ms", settings.GameBlackMovetimeMS},
+		{"game_search_mode", settings.GameSearchMode},
+		{"game_nodes", settings.GameNodes},
+		{"game_depth", settings.GameDepth},
+		{"game_slack_ms", settings.GameSlackMS},
+		{"game_book_path", settings.GameBookPath},
+		{"match_soft_scale", settings.MatchSoftScale},
+		{"match_allow_mirror", mirror},
+		{"game_fen_interval", settings.GameFENInterval},
+		{"game_store_pv", storePV},
+		{"game_clear_hash", clearHash},
+		{"game_draw_plies", settings.GameDrawPlies},
+		{"game_draw_cp", settings.GameDrawCP},
+		{"match_seed", settings.MatchSeed},
+		{"ranking_scoring", settings.RankingScoring},
+		{"ranking_draw_score", settings.RankingDrawScore},
+	}
+}
+
+func (s *Store) writeSettings(ctx context.Context, values []settingValue) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -80,33 +240,10 @@ func (s *Store) UpdateSettings(ctx context.Context, settings Settings) error {
 
 	upsert := `INSERT INTO settings (key, value) VALUES (?, ?)
 		ON CONFLICT(key) DO UPDATE SET value = excluded.value`
-	if _, err = tx.ExecContext(ctx, upsert, "opening_min", settings.OpeningMin); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "analysis_engine_id", settings.AnalysisEngineID); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "analysis_depth", settings.AnalysisDepth); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "game_movetime_ms", settings.GameMovetimeMS); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "game_slack_ms", settings.GameSlackMS); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "game_book_path", settings.GameBookPath); err != nil {
-		return err
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "match_soft_scale", settings.MatchSoftScale); err != nil {
-		return err
-	}
-	mirror := 0
-	if settings.MatchAllowMirror {
-		mirror = 1
-	}
-	if _, err = tx.ExecContext(ctx, upsert, "match_allow_mirror", mirror); err != nil {
-		return err
+	for _, v := range values {
+		if _, err = tx.ExecContext(ctx, upsert, v.key, v.value); err != nil {
+			return err
+		}
 	}
 
 	return tx.Commit()
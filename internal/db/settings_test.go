@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateChangedSettingsKeepsConcurrentSaves(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	before, err := s.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two pages saved from the same read, each changing a different field
+	first := before
+	first.GameNodes = 5000
+	second := before
+	second.RankingScoring = "football"
+	if err := s.UpdateChangedSettings(ctx, before, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateChangedSettings(ctx, before, second); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GameNodes != 5000 {
+		t.Errorf("GameNodes = %d, want 5000", got.GameNodes)
+	}
+	if got.RankingScoring != "football" {
+		t.Errorf("RankingScoring = %q, want football", got.RankingScoring)
+	}
+}
+
+func TestSeedOffsetStartsAboveExistingGames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	a := insertTestEngine(t, s, "a")
+	b := insertTestEngine(t, s, "b")
+	for i := 0; i < 3; i++ {
+		insertTestGame(t, s, a, b, "1-0")
+	}
+	// a database from before the offset was stored
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM settings WHERE key = 'seed_offset'`); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for want := int64(3); want < 5; want++ {
+		if got, err := s.NextSeedOffset(ctx); err != nil || got != want {
+			t.Fatalf("offset = %d (%v), want %d", got, err, want)
+		}
+	}
+}
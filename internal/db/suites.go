@@ -0,0 +1,264 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+
+	"tethys/internal/book"
+)
+
+// ParseEPD reads EPD records: the first four FEN fields followed by
+// semicolon-terminated opcodes. Of those, bm (best moves, in SAN), ce
+// (centipawn eval), id and the hmvc/fmvn move counters are used. Blank lines
+// and lines starting with # are skipped.
+func ParseEPD(r io.Reader) ([]SuitePosition, error) {
+	var out []SuitePosition
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parseEPDLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		out = append(out, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parseEPDLine(line string) (SuitePosition, error) {
+	var p SuitePosition
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return p, fmt.Errorf("expected at least four FEN fields")
+	}
+	fenKey := strings.Join(fields[:4], " ")
+	// the opcodes are whatever follows the fourth field
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[len(fields[i]):]
+	}
+
+	ops := splitEPDOps(rest)
+	halfmove, fullmove := "0", "1"
+	if v, ok := ops["hmvc"]; ok && len(v) == 1 {
+		halfmove = v[0]
+	}
+	if v, ok := ops["fmvn"]; ok && len(v) == 1 {
+		fullmove = v[0]
+	}
+	fenOpt, err := chess.FEN(fenKey + " " + halfmove + " " + fullmove)
+	if err != nil {
+		return p, fmt.Errorf("invalid position: %v", err)
+	}
+	pos := chess.NewGame(fenOpt).Position()
+	p.FEN = pos.String()
+	p.ZobristKey = book.ZobristKey(pos)
+
+	if v := ops["id"]; len(v) > 0 {
+		p.EPDID = strings.Join(v, " ")
+	}
+	if v := ops["ce"]; len(v) > 0 {
+		ce, err := strconv.Atoi(v[0])
+		if err != nil {
+			return p, fmt.Errorf("invalid ce %q", v[0])
+		}
+		p.CE = &ce
+	}
+	var best []string
+	for _, san := range ops["bm"] {
+		move, err := chess.AlgebraicNotation{}.Decode(pos, san)
+		if err != nil {
+			// some tools write bm in UCI
+			if move, err = (chess.UCINotation{}).Decode(pos, san); err != nil {
+				return p, fmt.Errorf("invalid bm %q", san)
+			}
+		}
+		best = append(best, chess.UCINotation{}.Encode(pos, move))
+	}
+	p.BestMoves = strings.Join(best, " ")
+	return p, nil
+}
+
+// splitEPDOps splits the opcode part of an EPD record into operand lists by
+// opcode. Quoted operands keep their spaces and semicolons.
+func splitEPDOps(s string) map[string][]string {
+	ops := make(map[string][]string)
+	var tokens []string
+	var cur strings.Builder
+	inQuote, quoted := false, false
+	flush := func() {
+		if cur.Len() > 0 || quoted {
+			tokens = append(tokens, cur.String())
+		}
+		cur.Reset()
+		quoted = false
+	}
+	for _, c := range s {
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+			quoted = true
+		case inQuote:
+			cur.WriteRune(c)
+		case c == ';':
+			flush()
+			if len(tokens) > 0 {
+				ops[tokens[0]] = tokens[1:]
+			}
+			tokens = nil
+		case c == ' ' || c == '\t':
+			flush()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	// tolerate a missing final semicolon
+	flush()
+	if len(tokens) > 0 {
+		ops[tokens[0]] = tokens[1:]
+	}
+	return ops
+}
+
+// ImportSuite replaces the positions of suite, dropping the results of
+// earlier runs.
+func (s *Store) ImportSuite(ctx context.Context, suite string, positions []SuitePosition) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM suite_positions WHERE suite = ?`, suite); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO suite_positions (suite, epd_id, fen, zobrist_key, best_moves, ce)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range positions {
+		if _, err = stmt.ExecContext(ctx, suite, p.EPDID, p.FEN, int64(p.ZobristKey), p.BestMoves, p.CE); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// imported suites with how many of their bm positions the last run solved
+func (s *Store) ListSuites(ctx context.Context) ([]SuiteSummary, error) {
+	var out []SuiteSummary
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT suite,
+			COUNT(*) AS positions,
+			SUM(CASE WHEN best_moves <> '' THEN 1 ELSE 0 END) AS with_bm,
+			SUM(CASE WHEN found_move <> '' THEN 1 ELSE 0 END) AS run,
+			SUM(CASE WHEN found_move <> '' AND instr(' ' || best_moves || ' ', ' ' || found_move || ' ') > 0 THEN 1 ELSE 0 END) AS solved
+		FROM suite_positions
+		GROUP BY suite
+		ORDER BY suite
+	`)
+	return out, err
+}
+
+// suiteRow carries the zobrist key as stored: database/sql refuses uint64
+// values with the high bit set, so the column holds the same bits as int64.
+type suiteRow struct {
+	SuitePosition
+	Key int64 `db:"zobrist_key"`
+}
+
+// the positions of a suite in file order
+func (s *Store) SuitePositions(ctx context.Context, suite string) ([]SuitePosition, error) {
+	var rows []suiteRow
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT id, suite, epd_id, fen, zobrist_key, best_moves, ce, found_move, found_score, engine_id, depth
+		FROM suite_positions
+		WHERE suite = ?
+		ORDER BY id
+	`, suite)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SuitePosition, len(rows))
+	for i, row := range rows {
+		out[i] = row.SuitePosition
+		out[i].ZobristKey = uint64(row.Key)
+	}
+	return out, nil
+}
+
+// record what the analysis engine played in a suite position
+func (s *Store) SetSuiteResult(ctx context.Context, id int64, move, score string, engineID int64, depth int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE suite_positions
+		SET found_move = ?, found_score = ?, engine_id = ?, depth = ?
+		WHERE id = ?
+	`, move, score, engineID, depth, id)
+	return err
+}
+
+// forget the results of the last run of a suite
+func (s *Store) ClearSuiteResults(ctx context.Context, suite string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE suite_positions
+		SET found_move = '', found_score = '', engine_id = 0, depth = 0
+		WHERE suite = ?
+	`, suite)
+	return err
+}
+
+// record a completed suite run
+func (s *Store) InsertSuiteRun(ctx context.Context, run SuiteRun) error {
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO suite_runs (suite, engine_id, depth, solved, total, elapsed_ms)
+		VALUES (:suite, :engine_id, :depth, :solved, :total, :elapsed_ms)
+	`, run)
+	return err
+}
+
+// SuiteLeaderboard returns each engine's best run of each suite, highest
+// share solved first; ties go to the faster run. A suite imported again may
+// have a different size, hence the share.
+func (s *Store) SuiteLeaderboard(ctx context.Context) ([]SuiteRun, error) {
+	var out []SuiteRun
+	err := s.db.SelectContext(ctx, &out, `
+		SELECT id, suite, engine_id, engine, finished_at, depth, solved, total, elapsed_ms
+		FROM (
+			SELECT r.id, r.suite, r.engine_id, p.name AS engine, r.finished_at, r.depth,
+				r.solved, r.total, r.elapsed_ms,
+				ROW_NUMBER() OVER (
+					PARTITION BY r.suite, r.engine_id
+					ORDER BY CAST(r.solved AS REAL) / MAX(r.total, 1) DESC, r.elapsed_ms ASC, r.id DESC
+				) AS rank
+			FROM suite_runs r
+			JOIN players p ON p.id = r.engine_id
+		)
+		WHERE rank = 1
+		ORDER BY suite, CAST(solved AS REAL) / MAX(total, 1) DESC, elapsed_ms ASC
+	`)
+	return out, err
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testEPD = `# two positions
+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 bm e5 c5; id "open; 1";
+6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - bm Ra8#; ce 32000; hmvc 4; fmvn 30; id "mate";
+r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - ce -15
+`
+
+func TestParseEPD(t *testing.T) {
+	positions, err := ParseEPD(strings.NewReader(testEPD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 3 {
+		t.Fatalf("parsed %d positions, want 3", len(positions))
+	}
+	first := positions[0]
+	if first.EPDID != "open; 1" || first.BestMoves != "e7e5 c7c5" || first.CE != nil {
+		t.Errorf("first position = %+v", first)
+	}
+	if first.ZobristKey != 0x823c9b50fd114196 {
+		t.Errorf("zobrist key %x, want the polyglot key after 1. e4", first.ZobristKey)
+	}
+	mate := positions[1]
+	if mate.BestMoves != "a1a8" || mate.CE == nil || *mate.CE != 32000 || !strings.HasSuffix(mate.FEN, " 4 30") {
+		t.Errorf("second position = %+v", mate)
+	}
+	if last := positions[2]; last.BestMoves != "" || last.CE == nil || *last.CE != -15 {
+		t.Errorf("third position = %+v", last)
+	}
+
+	if _, err := ParseEPD(strings.NewReader("8/8/8/8/8/8/8/K6k w - - bm Qh8;\n")); err == nil {
+		t.Error("an impossible bm was accepted")
+	}
+}
+
+func TestSuiteResults(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	positions, err := ParseEPD(strings.NewReader(testEPD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ImportSuite(ctx, "test", positions); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.SuitePositions(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 || stored[0].ZobristKey != positions[0].ZobristKey || stored[2].CE == nil {
+		t.Fatalf("stored positions = %+v", stored)
+	}
+	if err := s.SetSuiteResult(ctx, stored[0].ID, "c7c5", "cp 20", 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSuiteResult(ctx, stored[1].ID, "a1a7", "cp 500", 1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	suites, err := s.ListSuites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SuiteSummary{Suite: "test", Positions: 3, WithBM: 2, Run: 2, Solved: 1}
+	if len(suites) != 1 || suites[0] != want {
+		t.Errorf("ListSuites = %+v, want %+v", suites, want)
+	}
+	stored, err = s.SuitePositions(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored[0].Solved() || stored[1].Solved() || stored[2].Solved() {
+		t.Errorf("solved flags wrong: %+v", stored)
+	}
+
+	// importing again replaces the suite and its results
+	if err := s.ImportSuite(ctx, "test", positions[:1]); err != nil {
+		t.Fatal(err)
+	}
+	suites, err = s.ListSuites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 1 || suites[0].Positions != 1 || suites[0].Run != 0 {
+		t.Errorf("after re-import: %+v", suites)
+	}
+}
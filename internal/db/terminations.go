@@ -0,0 +1,71 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Labels stored in games.termination. Everything that ends a game uses these
+// instead of spelling out its own string.
+const (
+	TerminationCheckmate            = "Checkmate"
+	TerminationResignation          = "Resignation"
+	TerminationDrawAgreed           = "Draw agreed"
+	TerminationStalemate            = "Stalemate"
+	TerminationRepetition           = "Repetition"
+	TerminationFiftyMoves           = "Fifty-move rule"
+	TerminationInsufficientMaterial = "Insufficient material"
+	TerminationMaxPlies             = "Max plies"
+	TerminationAdjudicatedDraw      = "Adjudicated draw"
+	TerminationEngineCrash          = "Engine crash"
+	TerminationTimeout              = "Timeout"
+	TerminationNoMove               = "No move"
+	TerminationIllegalMove          = "Illegal move"
+	TerminationForfeitNoMove        = "Forfeit: no move"
+	TerminationAborted              = "Aborted"
+)
+
+// TerminationResigned labels an engine giving up with a non-move such as
+// '(none)', which is kept for debugging: "Resigned ((none))".
+func TerminationResigned(move string) string {
+	return "Resigned (" + move + ")"
+}
+
+// terminationNames maps raw termination strings (chess.Method names and the
+// runner's reasons from before the labels above) to the stored labels.
+var terminationNames = map[string]string{
+	"NoMethod":             "",
+	"Checkmate":            TerminationCheckmate,
+	"Resignation":          TerminationResignation,
+	"DrawOffer":            TerminationDrawAgreed,
+	"Stalemate":            TerminationStalemate,
+	"ThreefoldRepetition":  TerminationRepetition,
+	"FivefoldRepetition":   TerminationRepetition,
+	"FiftyMoveRule":        TerminationFiftyMoves,
+	"SeventyFiveMoveRule":  TerminationFiftyMoves,
+	"InsufficientMaterial": TerminationInsufficientMaterial,
+	"EngineCrash":          TerminationEngineCrash,
+	"NoMove":               TerminationNoMove,
+	"IllegalMove":          TerminationIllegalMove,
+}
+
+// NormalizeTermination returns the stored label for a raw termination.
+// Unknown values are kept as-is.
+func NormalizeTermination(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if name, ok := terminationNames[raw]; ok {
+		return name
+	}
+	return raw
+}
+
+// rewrite terminations stored before normalization
+func normalizeTerminations(db *sqlx.DB) {
+	for raw, name := range terminationNames {
+		if raw == name {
+			continue
+		}
+		db.MustExec(`UPDATE games SET termination = ? WHERE termination = ?`, name, raw)
+	}
+}
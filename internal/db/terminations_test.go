@@ -0,0 +1,24 @@
+package db
+
+import "testing"
+
+func TestNormalizeTermination(t *testing.T) {
+	tests := map[string]string{
+		"ThreefoldRepetition": TerminationRepetition,
+		"EngineCrash":         TerminationEngineCrash,
+		"NoMethod":            "",
+		" Timeout ":           TerminationTimeout,
+		"Resigned ((none))":   TerminationResigned("(none)"),
+	}
+	for raw, want := range tests {
+		if got := NormalizeTermination(raw); got != want {
+			t.Errorf("NormalizeTermination(%q) = %q, want %q", raw, got, want)
+		}
+	}
+	// stored labels are fixed points, or games would be relabeled on upgrade
+	for _, label := range terminationNames {
+		if got := NormalizeTermination(label); got != label {
+			t.Errorf("label %q normalizes to %q", label, got)
+		}
+	}
+}
@@ -1,14 +1,36 @@
 package db
 
+import (
+	"strings"
+	"time"
+)
+
 type Settings struct {
-	OpeningMin       int    `db:"opening_min"`
-	AnalysisEngineID int64  `db:"analysis_engine_id"`
-	AnalysisDepth    int    `db:"analysis_depth"`
-	GameMovetimeMS   int    `db:"game_movetime_ms"`
-	GameSlackMS      int    `db:"game_slack_ms"`
-	GameBookPath     string `db:"game_book_path"`
-	MatchSoftScale   int    `db:"match_soft_scale"`
-	MatchAllowMirror bool   `db:"match_allow_mirror"`
+	OpeningMin          int     `db:"opening_min"`
+	AnalysisEngineID    int64   `db:"analysis_engine_id"`
+	AnalysisDepth       int     `db:"analysis_depth"`
+	AnalysisMaxJobs     int     `db:"analysis_max_jobs"`
+	AnalysisThreads     int     `db:"analysis_threads"`
+	AnalysisHashMB      int     `db:"analysis_hash_mb"`
+	AnalysisNice        int     `db:"analysis_nice"`
+	GameMovetimeMS      int     `db:"game_movetime_ms"`
+	GameWhiteMovetimeMS int     `db:"game_white_movetime_ms"`
+	GameBlackMovetimeMS int     `db:"game_black_movetime_ms"`
+	GameSearchMode      string  `db:"game_search_mode"`
+	GameNodes           int     `db:"game_nodes"`
+	GameDepth           int     `db:"game_depth"`
+	GameSlackMS         int     `db:"game_slack_ms"`
+	GameFENInterval     int     `db:"game_fen_interval"`
+	GameStorePV         bool    `db:"game_store_pv"`
+	GameClearHash       bool    `db:"game_clear_hash"`
+	GameDrawPlies       int     `db:"game_draw_plies"`
+	GameDrawCP          int     `db:"game_draw_cp"`
+	GameBookPath        string  `db:"game_book_path"`
+	MatchSoftScale      int     `db:"match_soft_scale"`
+	MatchAllowMirror    bool    `db:"match_allow_mirror"`
+	MatchSeed           int64   `db:"match_seed"`
+	RankingScoring      string  `db:"ranking_scoring"`
+	RankingDrawScore    float64 `db:"ranking_draw_score"`
 }
 
 type GameDetail struct {
@@ -17,11 +39,15 @@ type GameDetail struct {
 	White       string `db:"white"`
 	Black       string `db:"black"`
 	MovetimeMS  int    `db:"movetime_ms"`
+	SearchLimit string `db:"search_limit"`
+	Seed        int64  `db:"seed"`
 	Result      string `db:"result"`
 	Termination string `db:"termination"`
 	MovesUCI    string `db:"moves_uci"`
 	Plies       int    `db:"ply_count"`
 	BookPlies   int    `db:"book_plies"`
+	ECO         string `db:"eco"`
+	Opening     string `db:"opening"`
 }
 
 type Eval struct {
@@ -40,41 +66,84 @@ type Engine struct {
 	Args string  `db:"engine_args"`
 	Init string  `db:"engine_init"`
 	Elo  float64 `db:"engine_elo"`
+	// SkipNewGame suppresses 'ucinewgame' for engines that mishandle it.
+	SkipNewGame bool `db:"skip_newgame"`
+	// StrengthElo caps play strength via UCI_Elo; 0 plays at full strength.
+	// Unlike Elo, which is the fitted rating, this is a setting.
+	StrengthElo int `db:"strength_elo"`
+	// Paused engines keep their settings and games but get no new games.
+	Paused bool `db:"paused"`
+	// freeform notes (commit, build flags, ...), shown in the UI only
+	Notes string `db:"notes"`
+	// comma-separated tags, see NormalizeTags
+	Tags string `db:"tags"`
+	// baseline from the last benchmark run, see SetEngineBench
+	BenchNodes int64  `db:"bench_nodes"`
+	BenchNPS   int64  `db:"bench_nps"`
+	BenchAt    string `db:"bench_at"`
 }
 
 type GameSearchFilter struct {
-	EngineID    int64
-	WhiteID     int64
-	BlackID     int64
-	AllowSwap   bool
-	MovetimeMS  int
-	Result      string
-	Termination string
+	EngineID      int64
+	WhiteID       int64
+	BlackID       int64
+	AllowSwap     bool
+	SearchLimit   string
+	Result        string
+	Termination   string
+	OpeningPrefix string
+	ECO           string
+	MinPlies      int
+	MaxPlies      int
+	Since         time.Time
+	Until         time.Time
+	// Order is one of the keys of searchOrders; anything else sorts by id
+	Order string
+
+	// IncludeUnfinished also lists games stored without a result, aborted
+	// or cut short. They are left out unless this, Status or a "*" Result
+	// asks for them.
+	IncludeUnfinished bool
+	// Status, one of the GameStatus values, keeps only games of that status
+	// and takes precedence over IncludeUnfinished
+	Status string
 }
 
+// Game statuses for GameSearchFilter.Status. A game without a result is
+// aborted if its termination says so and unfinished otherwise.
+const (
+	GameStatusFinished   = "finished"
+	GameStatusAborted    = "aborted"
+	GameStatusUnfinished = "unfinished"
+)
+
 type GameMovesRow struct {
 	MovesUCI string `db:"moves_uci"`
 	Result   string `db:"result"`
 }
 
 type GameQueueEntry struct {
-	ID         int64  `db:"id"`
-	CreatedAt  string `db:"created_at"`
-	WhiteID    int64  `db:"white_player_id"`
-	BlackID    int64  `db:"black_player_id"`
-	MovetimeMS int    `db:"movetime_ms"`
-	BookPath   string `db:"book_path"`
+	ID          int64  `db:"id"`
+	CreatedAt   string `db:"created_at"`
+	WhiteID     int64  `db:"white_player_id"`
+	BlackID     int64  `db:"black_player_id"`
+	MovetimeMS  int    `db:"movetime_ms"`
+	BookPath    string `db:"book_path"`
+	SearchLimit string `db:"search_limit"`
+	Priority    int    `db:"priority"`
 }
 
 type GameQueueRow struct {
-	ID         int64  `db:"id"`
-	CreatedAt  string `db:"created_at"`
-	WhiteID    int64  `db:"white_player_id"`
-	BlackID    int64  `db:"black_player_id"`
-	WhiteName  string `db:"white"`
-	BlackName  string `db:"black"`
-	MovetimeMS int    `db:"movetime_ms"`
-	BookPath   string `db:"book_path"`
+	ID          int64  `db:"id"`
+	CreatedAt   string `db:"created_at"`
+	WhiteID     int64  `db:"white_player_id"`
+	BlackID     int64  `db:"black_player_id"`
+	WhiteName   string `db:"white"`
+	BlackName   string `db:"black"`
+	MovetimeMS  int    `db:"movetime_ms"`
+	BookPath    string `db:"book_path"`
+	SearchLimit string `db:"search_limit"`
+	Priority    int    `db:"priority"`
 }
 
 type PairResult struct {
@@ -88,14 +157,14 @@ type PairResult struct {
 }
 
 type MatchupSummary struct {
-	AID        int64
-	BID        int64
-	A          string
-	B          string
-	MovetimeMS int
-	WinsA      int
-	WinsB      int
-	Draws      int
+	AID         int64
+	BID         int64
+	A           string
+	B           string
+	SearchLimit string
+	WinsA       int
+	WinsB       int
+	Draws       int
 }
 
 type MatchupCount struct {
@@ -109,3 +178,63 @@ type ResultSummary struct {
 	Termination string `db:"termination"`
 	Count       int    `db:"count"`
 }
+
+// SuitePosition is one position of an imported EPD test suite, with the
+// outcome of its last run.
+type SuitePosition struct {
+	ID         int64  `db:"id"`
+	Suite      string `db:"suite"`
+	EPDID      string `db:"epd_id"`
+	FEN        string `db:"fen"`
+	ZobristKey uint64 `db:"-"`
+	// BestMoves are the bm moves in UCI, space separated
+	BestMoves string `db:"best_moves"`
+	// CE is the ce eval in centipawns from the side to move, if given
+	CE         *int   `db:"ce"`
+	FoundMove  string `db:"found_move"`
+	FoundScore string `db:"found_score"`
+	EngineID   int64  `db:"engine_id"`
+	Depth      int    `db:"depth"`
+}
+
+// Solved reports whether the last run found one of the best moves.
+func (p SuitePosition) Solved() bool {
+	if p.FoundMove == "" {
+		return false
+	}
+	for _, m := range strings.Fields(p.BestMoves) {
+		if m == p.FoundMove {
+			return true
+		}
+	}
+	return false
+}
+
+type SuiteSummary struct {
+	Suite     string `db:"suite"`
+	Positions int    `db:"positions"`
+	WithBM    int    `db:"with_bm"`
+	Run       int    `db:"run"`
+	Solved    int    `db:"solved"`
+}
+
+// SuiteRun is one completed run of an engine over a test suite.
+type SuiteRun struct {
+	ID         int64  `db:"id"`
+	Suite      string `db:"suite"`
+	EngineID   int64  `db:"engine_id"`
+	Engine     string `db:"engine"`
+	FinishedAt string `db:"finished_at"`
+	Depth      int    `db:"depth"`
+	// Solved counts the positions where the engine played a bm move, out
+	// of Total positions that have one
+	Solved    int   `db:"solved"`
+	Total     int   `db:"total"`
+	ElapsedMS int64 `db:"elapsed_ms"`
+}
+
+// EloPoint is an engine's rating as of a recompute, see ReplaceEngineElos.
+type EloPoint struct {
+	At  string  `db:"at"`
+	Elo float64 `db:"elo"`
+}
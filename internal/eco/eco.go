@@ -0,0 +1,80 @@
+// Package eco names openings from a small bundled ECO table.
+package eco
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+//go:embed openings.tsv
+var openingsTSV string
+
+// Opening is an entry of the ECO table.
+type Opening struct {
+	ECO  string
+	Name string
+}
+
+var (
+	loadOnce sync.Once
+	// UCI move prefix ("e2e4 c7c5") -> opening
+	byPrefix map[string]Opening
+	// longest line in the table, in plies
+	maxPlies int
+)
+
+func load() {
+	table, longest, err := parseTable(openingsTSV)
+	if err != nil {
+		panic(err) // the table is embedded, so this is a build problem
+	}
+	byPrefix, maxPlies = table, longest
+}
+
+func parseTable(data string) (map[string]Opening, int, error) {
+	table := make(map[string]Opening)
+	longest := 0
+	for n, line := range strings.Split(data, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, 0, fmt.Errorf("openings.tsv:%d: want 3 tab-separated fields", n+1)
+		}
+		pos := chess.StartingPosition()
+		uci := make([]string, 0, 8)
+		for _, san := range strings.Fields(fields[2]) {
+			mv, err := chess.AlgebraicNotation{}.Decode(pos, san)
+			if err != nil {
+				return nil, 0, fmt.Errorf("openings.tsv:%d: %s: %w", n+1, san, err)
+			}
+			uci = append(uci, chess.UCINotation{}.Encode(pos, mv))
+			pos = pos.Update(mv)
+		}
+		key := strings.Join(uci, " ")
+		if _, dup := table[key]; dup {
+			return nil, 0, fmt.Errorf("openings.tsv:%d: duplicate line %s", n+1, fields[2])
+		}
+		table[key] = Opening{ECO: fields[0], Name: fields[1]}
+		longest = max(longest, len(uci))
+	}
+	return table, longest, nil
+}
+
+// Classify returns the opening of the longest table line that the game's
+// moves (space-separated UCI) start with.
+func Classify(movesUCI string) (Opening, bool) {
+	loadOnce.Do(load)
+	moves := strings.Fields(movesUCI)
+	for n := min(len(moves), maxPlies); n > 0; n-- {
+		if o, ok := byPrefix[strings.Join(moves[:n], " ")]; ok {
+			return o, true
+		}
+	}
+	return Opening{}, false
+}
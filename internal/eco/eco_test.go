@@ -0,0 +1,39 @@
+package eco
+
+import "testing"
+
+func TestTableParses(t *testing.T) {
+	table, _, err := parseTable(openingsTSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) < 100 {
+		t.Fatalf("only %d distinct lines in the table", len(table))
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		moves string
+		eco   string
+		name  string
+	}{
+		{"e2e4 c7c5 g1f3 d7d6 d2d4 c5d4 f3d4 g8f6 b1c3 a7a6 c1e3", "B90", "Sicilian Defense: Najdorf Variation"},
+		{"e2e4 c7c5 g1f3 d7d6 d2d4 c5d4 f3d4", "B54", "Sicilian Defense: Open"},
+		{"e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7", "C84", "Ruy Lopez: Closed"},
+		{"d2d4 g8f6 c2c4 e7e6 g1f3 f8b4", "E11", "Bogo-Indian Defense"},
+		{"e2e4 a7a5", "B00", "King's Pawn Game"},
+	}
+	for _, tc := range tests {
+		o, ok := Classify(tc.moves)
+		if !ok || o.ECO != tc.eco || o.Name != tc.name {
+			t.Errorf("Classify(%q) = %+v, %v, want %s %s", tc.moves, o, ok, tc.eco, tc.name)
+		}
+	}
+	if o, ok := Classify("h2h3"); ok {
+		t.Errorf("Classify(h2h3) = %+v, want no match", o)
+	}
+	if _, ok := Classify(""); ok {
+		t.Error("Classify of an empty game matched")
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -23,22 +24,52 @@ type AnalysisInfo struct {
 	Depth      int
 	UpdatedAt  time.Time
 	Done       bool
-	Err        string
+	// Queued is set while the job waits for a free analysis engine.
+	Queued bool
+	Err    string
 }
 
 type Analyzer struct {
-	store *db.Store
+	store     *db.Store
+	newEngine EngineFactory
 
 	mu     sync.Mutex
 	jobs   map[uint64]context.CancelFunc
 	latest map[uint64]AnalysisInfo
+
+	// running engines, capped at maxJobs; jobs over the cap wait in order
+	running int
+	maxJobs int
+	waiting []chan struct{}
+
+	// idle engines kept alive between positions, at most maxJobs
+	idle   []*analysisEngine
+	closed bool
+	// engines in use by a job or suite run; Close kills them
+	busy map[Engine]bool
+	// jobs and suite runs in progress, waited for by Close
+	wg sync.WaitGroup
+
+	// test suites being run, by name
+	suites map[string]context.CancelFunc
+}
+
+// analysisEngine is a started, initialized engine together with the setup
+// it was started from.
+type analysisEngine struct {
+	row  db.Engine
+	nice int
+	eng  Engine
 }
 
 func NewAnalyzer(store *db.Store) *Analyzer {
 	return &Analyzer{
-		store:  store,
-		jobs:   make(map[uint64]context.CancelFunc),
-		latest: make(map[uint64]AnalysisInfo),
+		store:     store,
+		newEngine: newUCIEngine,
+		jobs:      make(map[uint64]context.CancelFunc),
+		latest:    make(map[uint64]AnalysisInfo),
+		busy:      make(map[Engine]bool),
+		suites:    make(map[string]context.CancelFunc),
 	}
 }
 
@@ -73,9 +104,10 @@ func (a *Analyzer) EnsureAnalysis(ctx context.Context, fen string) (AnalysisInfo
 	if latest, ok := a.latest[key]; ok {
 		info = mergeAnalysis(info, latest)
 	}
-	if _, running := a.jobs[key]; !running {
+	if _, running := a.jobs[key]; !running && !a.closed {
 		jobCtx, cancel := context.WithCancel(context.Background())
 		a.jobs[key] = cancel
+		a.wg.Add(1)
 		go a.run(jobCtx, key, fenKey, fullFen)
 	}
 	a.latest[key] = info
@@ -85,6 +117,7 @@ func (a *Analyzer) EnsureAnalysis(ctx context.Context, fen string) (AnalysisInfo
 }
 
 func (a *Analyzer) run(ctx context.Context, key uint64, fenKey string, fullFen string) {
+	defer a.wg.Done()
 	defer func() {
 		a.mu.Lock()
 		delete(a.jobs, key)
@@ -107,36 +140,82 @@ func (a *Analyzer) run(ctx context.Context, key uint64, fenKey string, fullFen s
 		a.updateError(key, fenKey, "analysis engine missing")
 		return
 	}
-	eng := NewUCIEngine(engRow.Path, strings.Fields(engRow.Args))
-	if err := eng.Start(ctx); err != nil {
-		a.updateError(key, fenKey, fmt.Sprintf("engine start error: %v", err))
+	engRow = analysisSetup(engRow, cfg)
+	if err := a.acquire(ctx, key, cfg.AnalysisMaxJobs); err != nil {
 		return
 	}
-	defer func() { _ = eng.Close() }()
-	if err := applyInit(ctx, eng, engRow.Init); err != nil {
-		a.updateError(key, fenKey, fmt.Sprintf("engine init error: %v", err))
+	defer a.release()
+	ae, err := a.checkout(ctx, engRow, cfg.AnalysisNice)
+	if err != nil {
+		a.updateError(key, fenKey, err.Error())
 		return
 	}
-	if err := eng.Send("position fen " + fullFen); err != nil {
-		a.updateError(key, fenKey, fmt.Sprintf("position error: %v", err))
+	// only an engine that finished its search cleanly goes back to the pool;
+	// one that was stopped, timed out or failed is killed
+	finished := false
+	defer func() {
+		if finished {
+			a.checkin(ae)
+		} else {
+			a.discard(ae.eng)
+		}
+	}()
+	_, err = searchDepth(ctx, ae.eng, fullFen, depth, func(depthVal int, score, pv string) {
+		a.updateLatest(AnalysisInfo{
+			ZobristKey: key,
+			FEN:        fenKey,
+			Score:      score,
+			PV:         pv,
+			EngineID:   engineID,
+			Depth:      depthVal,
+			UpdatedAt:  time.Now(),
+		})
+	})
+	if err != nil {
+		a.updateError(key, fenKey, err.Error())
 		return
 	}
+	finished = true
+	a.updateDone(key)
+}
+
+// analysisTimeout caps a single depth-limited analysis search, so that a
+// hung engine gives its analysis slot back.
+const analysisTimeout = 5 * time.Minute
+
+// stopGrace is how long a search that was stopped gets to send its bestmove.
+const stopGrace = time.Second
+
+// searchDepth searches fullFen to depth, passing every deeper info line to
+// onInfo, and returns the engine's best move. If ctx ends or the search takes
+// longer than analysisTimeout, the engine is told to stop and an error is
+// returned; the engine should not be reused then.
+func searchDepth(ctx context.Context, eng Engine, fullFen string, depth int, onInfo func(depth int, score, pv string)) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, analysisTimeout)
+	defer cancel()
+	if err := eng.Send("position fen " + fullFen); err != nil {
+		return "", fmt.Errorf("position error: %v", err)
+	}
 	if err := eng.Send(fmt.Sprintf("go depth %d", depth)); err != nil {
-		a.updateError(key, fenKey, fmt.Sprintf("go error: %v", err))
-		return
+		return "", fmt.Errorf("go error: %v", err)
 	}
 
 	latestDepth := 0
 	for {
-		line, err := eng.ReadLine()
+		line, err := eng.ReadLine(ctx)
+		if err != nil && ctx.Err() != nil {
+			stopSearch(eng)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf("analysis timed out after %s", analysisTimeout)
+			}
+			return "", ctx.Err()
+		}
 		if err != nil {
-			a.updateError(key, fenKey, fmt.Sprintf("engine read error: %v", err))
-			return
+			return "", fmt.Errorf("engine read error: %v", err)
 		}
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "bestmove ") {
-			a.updateDone(key)
-			return
+			return strings.Fields(line)[1], nil
 		}
 		depthVal, score, pv, ok := parseInfoLine(line)
 		if !ok {
@@ -146,16 +225,249 @@ func (a *Analyzer) run(ctx context.Context, key uint64, fenKey string, fullFen s
 			continue
 		}
 		latestDepth = depthVal
-		update := AnalysisInfo{
-			ZobristKey: key,
-			FEN:        fenKey,
-			Score:      score,
-			PV:         pv,
-			EngineID:   engineID,
-			Depth:      depthVal,
-			UpdatedAt:  time.Now(),
+		onInfo(depthVal, score, pv)
+	}
+}
+
+// stopSearch sends 'stop' and waits up to stopGrace for the bestmove.
+func stopSearch(eng Engine) {
+	if err := eng.Send("stop"); err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stopGrace)
+	defer cancel()
+	for {
+		line, err := eng.ReadLine(ctx)
+		if err != nil || strings.HasPrefix(line, "bestmove") {
+			return
+		}
+	}
+}
+
+// checkout returns an idle engine started from row's setup at the given nice
+// level, or starts one. Idle engines of another setup are closed: the
+// analysis engine, or its arguments, changed since they ran.
+func (a *Analyzer) checkout(ctx context.Context, row db.Engine, nice int) (*analysisEngine, error) {
+	a.mu.Lock()
+	var reuse *analysisEngine
+	var stale []*analysisEngine
+	kept := a.idle[:0]
+	for _, ae := range a.idle {
+		switch {
+		case !sameSetup(ae.row, row) || ae.nice != nice:
+			stale = append(stale, ae)
+		case reuse == nil:
+			reuse = ae
+		default:
+			kept = append(kept, ae)
+		}
+	}
+	a.idle = kept
+	a.mu.Unlock()
+	for _, ae := range stale {
+		_ = ae.eng.Close()
+	}
+
+	if reuse != nil {
+		// the process may have died while idle
+		if err := reuse.eng.IsReady(ctx); err == nil {
+			if err := a.track(reuse.eng); err != nil {
+				return nil, err
+			}
+			return reuse, nil
+		}
+		_ = reuse.eng.Close()
+	}
+
+	eng, err := a.startEngine(ctx, row, nice)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.track(eng); err != nil {
+		return nil, err
+	}
+	return &analysisEngine{row: row, nice: nice, eng: eng}, nil
+}
+
+// track marks eng as in use, so that Close kills it. After Close it closes
+// eng instead and fails.
+func (a *Analyzer) track(eng Engine) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		_ = eng.Close()
+		return errors.New("analyzer closed")
+	}
+	a.busy[eng] = true
+	a.mu.Unlock()
+	return nil
+}
+
+// discard closes an engine that is no longer used, unless Close already did.
+func (a *Analyzer) discard(eng Engine) {
+	a.mu.Lock()
+	owned := a.busy[eng]
+	delete(a.busy, eng)
+	a.mu.Unlock()
+	if owned {
+		_ = eng.Close()
+	}
+}
+
+// startEngine starts and initializes a new engine from row's setup, at a
+// lower priority if nice is above 0 and the engine supports it.
+func (a *Analyzer) startEngine(ctx context.Context, row db.Engine, nice int) (Engine, error) {
+	args, err := SplitArgs(row.Args)
+	if err != nil {
+		return nil, fmt.Errorf("engine args error: %v", err)
+	}
+	eng := a.newEngine(row.Path, args)
+	if n, ok := eng.(interface{ SetNice(int) }); ok && nice > 0 {
+		n.SetNice(nice)
+	}
+	if err := eng.Start(ctx); err != nil {
+		return nil, fmt.Errorf("engine start error: %v", err)
+	}
+	if err := applyInit(ctx, eng, row.Init, 0); err != nil {
+		_ = eng.Close()
+		return nil, fmt.Errorf("engine init error: %v", err)
+	}
+	return eng, nil
+}
+
+// checkin keeps ae for the next position, or closes it if the pool is full.
+func (a *Analyzer) checkin(ae *analysisEngine) {
+	a.mu.Lock()
+	owned := a.busy[ae.eng]
+	delete(a.busy, ae.eng)
+	if owned && !a.closed && len(a.idle) < a.maxJobs {
+		a.idle = append(a.idle, ae)
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+	if owned {
+		_ = ae.eng.Close()
+	}
+}
+
+// Close cancels the running analysis jobs and suite runs, kills their
+// engines and the idle ones, and waits for the jobs to finish. No new
+// jobs start afterwards.
+func (a *Analyzer) Close() {
+	a.mu.Lock()
+	engines := make([]Engine, 0, len(a.idle)+len(a.busy))
+	for _, ae := range a.idle {
+		engines = append(engines, ae.eng)
+	}
+	for eng := range a.busy {
+		engines = append(engines, eng)
+	}
+	a.idle = nil
+	a.busy = make(map[Engine]bool)
+	a.closed = true
+	for _, cancel := range a.jobs {
+		cancel()
+	}
+	for _, cancel := range a.suites {
+		cancel()
+	}
+	a.mu.Unlock()
+	for _, eng := range engines {
+		_ = eng.Close()
+	}
+	a.wg.Wait()
+}
+
+// analysisSetup appends the analysis Threads and Hash settings to the
+// engine's init commands, so they win over the engine's own, and a change
+// to them retires pooled engines like any other setup change.
+func analysisSetup(row db.Engine, cfg db.Settings) db.Engine {
+	var extra []string
+	if cfg.AnalysisThreads > 0 {
+		extra = append(extra, fmt.Sprintf("setoption name Threads value %d", cfg.AnalysisThreads))
+	}
+	if cfg.AnalysisHashMB > 0 {
+		extra = append(extra, fmt.Sprintf("setoption name Hash value %d", cfg.AnalysisHashMB))
+	}
+	if len(extra) > 0 {
+		row.Init = strings.TrimRight(row.Init, "\n") + "\n" + strings.Join(extra, "\n")
+	}
+	return row
+}
+
+// sameSetup reports whether an engine started from a can stand in for b.
+func sameSetup(a, b db.Engine) bool {
+	return a.ID == b.ID && a.Path == b.Path && a.Args == b.Args && a.Init == b.Init
+}
+
+// acquire waits until fewer than limit analysis engines run, marking the
+// position queued meanwhile. A limit below 1 counts as 1.
+func (a *Analyzer) acquire(ctx context.Context, key uint64, limit int) error {
+	return a.acquireSlot(ctx, limit, func(queued bool) {
+		curr := a.latest[key]
+		curr.Queued = queued
+		a.latest[key] = curr
+	})
+}
+
+// acquireSlot waits like acquire, calling setQueued with a.mu held when the
+// wait starts and ends, if it is not nil. Suite runs pass nil: they are not
+// positions anyone looks up.
+func (a *Analyzer) acquireSlot(ctx context.Context, limit int, setQueued func(queued bool)) error {
+	if setQueued == nil {
+		setQueued = func(bool) {}
+	}
+	a.mu.Lock()
+	a.maxJobs = max(limit, 1)
+	if len(a.waiting) == 0 && a.running < a.maxJobs {
+		a.running++
+		a.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	a.waiting = append(a.waiting, ready)
+	setQueued(true)
+	// the limit may have been raised since the last release
+	a.wakeLocked()
+	a.mu.Unlock()
+
+	select {
+	case <-ready:
+		a.mu.Lock()
+		setQueued(false)
+		a.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		setQueued(false)
+		for i, ch := range a.waiting {
+			if ch == ready {
+				a.waiting = append(a.waiting[:i], a.waiting[i+1:]...)
+				return ctx.Err()
+			}
 		}
-		a.updateLatest(update)
+		// woken as the context ended: hand the slot on
+		a.running--
+		a.wakeLocked()
+		return ctx.Err()
+	}
+}
+
+func (a *Analyzer) release() {
+	a.mu.Lock()
+	a.running--
+	a.wakeLocked()
+	a.mu.Unlock()
+}
+
+// wakeLocked starts waiting jobs while slots are free. a.mu must be held.
+func (a *Analyzer) wakeLocked() {
+	for len(a.waiting) > 0 && a.running < a.maxJobs {
+		a.running++
+		close(a.waiting[0])
+		a.waiting = a.waiting[1:]
 	}
 }
 
@@ -215,6 +527,7 @@ func mergeAnalysis(base AnalysisInfo, other AnalysisInfo) AnalysisInfo {
 		base.UpdatedAt = other.UpdatedAt
 	}
 	base.Done = other.Done
+	base.Queued = other.Queued
 	if other.Err != "" {
 		base.Err = other.Err
 	}
@@ -0,0 +1,315 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tethys/internal/db"
+)
+
+func TestAnalyzerQueuesOverLimit(t *testing.T) {
+	a := NewAnalyzer(nil)
+	ctx := context.Background()
+	if err := a.acquire(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	go func() {
+		if err := a.acquire(ctx, 2, 1); err == nil {
+			close(started)
+		}
+	}()
+	deadline := time.Now().Add(time.Second)
+	for info, _ := a.Latest(2); !info.Queued; info, _ = a.Latest(2) {
+		if time.Now().After(deadline) {
+			t.Fatal("second job never reported queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-started:
+		t.Fatal("second job started while the only slot was taken")
+	default:
+	}
+
+	a.release()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("second job did not start after the first finished")
+	}
+	if info, _ := a.Latest(2); info.Queued {
+		t.Error("running job still reported queued")
+	}
+}
+
+func TestAnalyzerCanceledWaiterFreesItsPlace(t *testing.T) {
+	a := NewAnalyzer(nil)
+	if err := a.acquire(context.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := a.acquire(ctx, 2, 1); err == nil {
+		t.Fatal("canceled job got a slot")
+	}
+	a.release()
+	if err := a.acquire(context.Background(), 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if a.running != 1 || len(a.waiting) != 0 {
+		t.Errorf("running %d, waiting %d; want 1 and 0", a.running, len(a.waiting))
+	}
+}
+
+// uciStub answers 'go' with one info line and a bestmove and counts how often
+// it was started and closed.
+type uciStub struct {
+	mu      sync.Mutex
+	pending []string
+	starts  *int
+	closes  *int
+}
+
+func (e *uciStub) Start(ctx context.Context) error   { *e.starts++; return nil }
+func (e *uciStub) Close() error                      { *e.closes++; return nil }
+func (e *uciStub) Options() []string                 { return nil }
+func (e *uciStub) IsReady(ctx context.Context) error { return nil }
+func (e *uciStub) NewGame(ctx context.Context) error { return nil }
+
+func (e *uciStub) Send(line string) error {
+	if strings.HasPrefix(line, "go ") {
+		e.mu.Lock()
+		e.pending = append(e.pending, "info depth 1 score cp 12 pv e2e4", "bestmove e2e4")
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+func (e *uciStub) ReadLine(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) == 0 {
+		return "", io.EOF
+	}
+	line := e.pending[0]
+	e.pending = e.pending[1:]
+	return line, nil
+}
+
+func (e *uciStub) BestMoveFrom(ctx context.Context, fen string, movesUCI []string, limit SearchLimit) (string, []string, error) {
+	return "", nil, io.EOF
+}
+
+func TestAnalyzerReusesEngine(t *testing.T) {
+	store, err := db.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	id, err := store.InsertEngine(ctx, db.Engine{Name: "stub", Path: "stub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := store.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.AnalysisEngineID = id
+	if err := store.UpdateSettings(ctx, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	var starts, closes int
+	a := NewAnalyzer(store)
+	a.newEngine = func(path string, args []string) Engine {
+		return &uciStub{starts: &starts, closes: &closes}
+	}
+	analyze := func(fen string) {
+		t.Helper()
+		info, err := a.EnsureAnalysis(ctx, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			a.mu.Lock()
+			_, running := a.jobs[info.ZobristKey]
+			a.mu.Unlock()
+			if !running {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("analysis of %s never finished", fen)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got, _ := a.Latest(info.ZobristKey); !got.Done || got.Score != "cp 12" || got.Err != "" {
+			t.Fatalf("analysis of %s = %+v", fen, got)
+		}
+	}
+
+	analyze("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	analyze("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if starts != 1 || closes != 0 {
+		t.Fatalf("two positions took %d starts and %d closes, want 1 and 0", starts, closes)
+	}
+
+	// changed arguments need a fresh process
+	if err := store.UpdateEngine(ctx, db.Engine{ID: id, Name: "stub", Path: "stub", Args: "--threads 2"}); err != nil {
+		t.Fatal(err)
+	}
+	analyze("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if starts != 2 || closes != 1 {
+		t.Fatalf("after the args changed: %d starts and %d closes, want 2 and 1", starts, closes)
+	}
+
+	a.Close()
+	if closes != 2 {
+		t.Errorf("Close left %d engines running", starts-closes)
+	}
+}
+
+func TestAnalysisSetup(t *testing.T) {
+	row := db.Engine{ID: 1, Path: "sf", Init: "setoption name Threads value 8\n"}
+	if got := analysisSetup(row, db.Settings{}); got != row {
+		t.Errorf("no analysis options changed the setup to %+v", got)
+	}
+	got := analysisSetup(row, db.Settings{AnalysisThreads: 2, AnalysisHashMB: 256})
+	want := "setoption name Threads value 8\nsetoption name Threads value 2\nsetoption name Hash value 256"
+	if got.Init != want {
+		t.Errorf("init = %q, want %q", got.Init, want)
+	}
+	if sameSetup(got, row) {
+		t.Error("changed analysis options must not reuse pooled engines")
+	}
+}
+
+// hungStub never finishes a search on its own and only answers 'stop'.
+type hungStub struct {
+	uciStub
+	stopped chan struct{}
+}
+
+func (e *hungStub) Send(line string) error {
+	if line == "stop" {
+		close(e.stopped)
+	}
+	return nil
+}
+
+func (e *hungStub) ReadLine(ctx context.Context) (string, error) {
+	select {
+	case <-e.stopped:
+		return "bestmove e2e4", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// startHungAnalysis starts analyzing the initial position with a hungStub
+// and returns once the job holds its analysis slot.
+func startHungAnalysis(t *testing.T) (*Analyzer, *hungStub, *int, uint64) {
+	t.Helper()
+	store, err := db.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	id, err := store.InsertEngine(ctx, db.Engine{Name: "stub", Path: "stub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := store.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.AnalysisEngineID = id
+	if err := store.UpdateSettings(ctx, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	var starts, closes int
+	eng := &hungStub{uciStub: uciStub{starts: &starts, closes: &closes}, stopped: make(chan struct{})}
+	a := NewAnalyzer(store)
+	a.newEngine = func(path string, args []string) Engine { return eng }
+	info, err := a.EnsureAnalysis(ctx, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		// once it holds the slot, the job goes on to start the engine
+		a.mu.Lock()
+		searching := a.running == 1
+		a.mu.Unlock()
+		if searching {
+			return a, eng, &closes, info.ZobristKey
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("analysis never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAnalyzerCanceledSearchDiscardsEngine(t *testing.T) {
+	a, eng, closes, key := startHungAnalysis(t)
+	a.mu.Lock()
+	a.jobs[key]()
+	a.mu.Unlock()
+
+	select {
+	case <-eng.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("canceled search was not told to stop")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.mu.Lock()
+		_, running := a.jobs[key]
+		slots, idle := a.running, len(a.idle)
+		a.mu.Unlock()
+		if !running {
+			if slots != 0 || idle != 0 || *closes != 1 {
+				t.Errorf("after the cancel: %d slots taken, %d idle engines, %d closes; want 0, 0, 1", slots, idle, *closes)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("canceled analysis never finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAnalyzerCloseStopsRunningJobs(t *testing.T) {
+	a, _, closes, key := startHungAnalysis(t)
+	closed := make(chan struct{})
+	go func() {
+		a.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not wait out the running job")
+	}
+	if _, running := a.jobs[key]; running || *closes != 1 {
+		t.Errorf("after Close: job running %v, %d closes; want false, 1", running, *closes)
+	}
+	if _, err := a.EnsureAnalysis(context.Background(), "8/8/8/8/8/8/8/K6k w - - 0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.jobs) != 0 {
+		t.Error("a job started after Close")
+	}
+}
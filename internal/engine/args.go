@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitArgs splits an engine argument string like a POSIX shell would, minus
+// expansion: whitespace separates arguments, single quotes keep everything
+// literally, double quotes keep whitespace and allow \" and \\, and a
+// backslash outside quotes escapes the next character.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, c := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case quote == '"':
+			switch c {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				cur.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			inArg = true
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in arguments", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in arguments")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"-a -b", []string{"-a", "-b"}},
+		{"  -a\t -b  ", []string{"-a", "-b"}},
+		{`"/opt/my engines/sf"`, []string{"/opt/my engines/sf"}},
+		{`--book '/data/my book.bin' -v`, []string{"--book", "/data/my book.bin", "-v"}},
+		{`--opt="value with space"`, []string{"--opt=value with space"}},
+		{`--opt='it''s'`, []string{"--opt=its"}},
+		{`"say \"hi\"" 'back\slash'`, []string{`say "hi"`, `back\slash`}},
+		{`path\ with\ spaces`, []string{"path with spaces"}},
+		{`"" x`, []string{"", "x"}},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.in)
+		if err != nil {
+			t.Errorf("SplitArgs(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgsErrors(t *testing.T) {
+	for _, in := range []string{`"open`, `'open`, `trailing\`} {
+		if got, err := SplitArgs(in); err == nil {
+			t.Errorf("SplitArgs(%q) = %q, want an error", in, got)
+		}
+	}
+}
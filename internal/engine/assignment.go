@@ -1,7 +1,11 @@
 package engine
 
 import (
+	"fmt"
 	"math"
+	"math/rand"
+	"sort"
+	"strconv"
 	"strings"
 
 	"tethys/internal/db"
@@ -10,9 +14,10 @@ import (
 type ColorAssignment struct {
 	White       db.Engine
 	Black       db.Engine
-	MovetimeMS  int
+	Limit       SearchLimit
 	BookEnabled bool
 	BookPath    string
+	Seed        int64
 }
 
 type matchupPair struct {
@@ -27,10 +32,11 @@ type weightedMatchupPair struct {
 	Weight   float64
 }
 
+// eligibleEngines drops incomplete and paused engines.
 func eligibleEngines(engines []db.Engine) []db.Engine {
 	eligible := make([]db.Engine, 0, len(engines))
 	for _, e := range engines {
-		if e.ID == 0 || e.Name == "" || e.Path == "" {
+		if IneligibleReason(e) != "" {
 			continue
 		}
 		eligible = append(eligible, e)
@@ -38,6 +44,29 @@ func eligibleEngines(engines []db.Engine) []db.Engine {
 	return eligible
 }
 
+// IneligibleReason says why an engine gets no games, or "" if it does.
+func IneligibleReason(e db.Engine) string {
+	switch {
+	case e.ID == 0 || e.Name == "":
+		return "is incomplete"
+	case e.Path == "":
+		return "has no path"
+	case e.Paused:
+		return "is paused"
+	}
+	return ""
+}
+
+// PairCount is the number of pairs the scheduler plays from these engines.
+func PairCount(engines []db.Engine, allowMirror bool) int {
+	n := len(eligibleEngines(engines))
+	pairs := n * (n - 1) / 2
+	if allowMirror {
+		pairs += n
+	}
+	return pairs
+}
+
 func buildDistanceWeightedPairs(engines []db.Engine, softScale int, allowMirror bool) []weightedMatchupPair {
 	eligible := eligibleEngines(engines)
 	if len(eligible) == 0 {
@@ -77,24 +106,225 @@ func buildDistanceWeightedPairs(engines []db.Engine, softScale int, allowMirror
 	return pairs
 }
 
-func assignmentFromQueue(entry db.GameQueueEntry, enginesByID map[int64]db.Engine) (ColorAssignment, bool) {
-	white, ok := enginesByID[entry.WhiteID]
-	if !ok {
-		return ColorAssignment{}, false
+// pairCount is a candidate pair with the games it has played in each color
+// assignment; A is always the lower engine ID.
+type pairCount struct {
+	AID      int64
+	BID      int64
+	AB       int
+	BA       int
+	Distance float64
+	Weight   float64
+}
+
+// planQueue picks the next batch of games. Pairs are ranked by weight over
+// games played, so every pair keeps being played but close ones more often.
+// The top pairs get four games each (two for a mirror pair), with colors
+// chosen so that each pair's White/Black counts even out.
+func planQueue(engines []db.Engine, counts []db.MatchupCount, settings db.Settings) []db.GameQueueEntry {
+	weightedPairs := buildDistanceWeightedPairs(engines, settings.MatchSoftScale, settings.MatchAllowMirror)
+	if len(weightedPairs) == 0 {
+		return nil
 	}
-	black, ok := enginesByID[entry.BlackID]
-	if !ok {
-		return ColorAssignment{}, false
+
+	pairCounts := make(map[[2]int64]*pairCount, len(weightedPairs))
+	for _, pair := range weightedPairs {
+		// engines come sorted by Elo, so either ID may be the lower one
+		a, b := pair.AID, pair.BID
+		if a > b {
+			a, b = b, a
+		}
+		pairCounts[[2]int64{a, b}] = &pairCount{AID: a, BID: b, Distance: pair.Distance, Weight: pair.Weight}
+	}
+	for _, c := range counts {
+		key := [2]int64{c.WhiteID, c.BlackID}
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		pc, ok := pairCounts[key]
+		if !ok {
+			continue
+		}
+		if c.WhiteID == pc.AID {
+			pc.AB += c.Count
+		} else {
+			pc.BA += c.Count
+		}
+	}
+
+	selected := make([]*pairCount, 0, len(pairCounts))
+	for _, pc := range pairCounts {
+		selected = append(selected, pc)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		totalI := selected[i].AB + selected[i].BA
+		totalJ := selected[j].AB + selected[j].BA
+		scoreI := selected[i].Weight / float64(1+totalI)
+		scoreJ := selected[j].Weight / float64(1+totalJ)
+		if math.Abs(scoreI-scoreJ) > 1e-9 {
+			return scoreI > scoreJ
+		}
+		if totalI != totalJ {
+			return totalI < totalJ
+		}
+		if selected[i].Distance != selected[j].Distance {
+			return selected[i].Distance < selected[j].Distance
+		}
+		if selected[i].AID != selected[j].AID {
+			return selected[i].AID < selected[j].AID
+		}
+		return selected[i].BID < selected[j].BID
+	})
+
+	eligibleCount := len(eligibleEngines(engines))
+	targetPairs := eligibleCount * 2
+	if targetPairs < 4 {
+		targetPairs = 4
+	}
+	if targetPairs > 32 {
+		targetPairs = 32
+	}
+	if targetPairs > len(selected) {
+		targetPairs = len(selected)
+	}
+
+	limit := settingsLimit(settings)
+	entry := func(white, black int64) db.GameQueueEntry {
+		return db.GameQueueEntry{
+			WhiteID:     white,
+			BlackID:     black,
+			MovetimeMS:  limit.MovetimeMS(),
+			BookPath:    settings.GameBookPath,
+			SearchLimit: limit.String(),
+		}
+	}
+	entries := make([]db.GameQueueEntry, 0, targetPairs*4)
+	for _, pc := range selected[:targetPairs] {
+		if pc.AID == pc.BID {
+			entries = append(entries, entry(pc.AID, pc.BID), entry(pc.AID, pc.BID))
+			continue
+		}
+		aWhite := balancedWhiteGames(pc.AB, pc.BA, 4)
+		for i, bWhite := 0, 4-aWhite; aWhite > 0 || bWhite > 0; i++ {
+			// alternate while both colors are left, so a batch cut short
+			// by a queue reset stays balanced
+			if aWhite > 0 && (i%2 == 0 || bWhite == 0) {
+				entries = append(entries, entry(pc.AID, pc.BID))
+				aWhite--
+			} else {
+				entries = append(entries, entry(pc.BID, pc.AID))
+				bWhite--
+			}
+		}
+	}
+	return entries
+}
+
+// balancedWhiteGames returns how many of n new games A should play as White
+// to bring its White (ab) and Black (ba) counts against B closest to even.
+func balancedWhiteGames(ab, ba, n int) int {
+	want := (ab+ba+n)/2 - ab
+	if want < 0 {
+		return 0
+	}
+	if want > n {
+		return n
+	}
+	return want
+}
+
+// settingsLimit returns the search limit configured for new games.
+func settingsLimit(settings db.Settings) SearchLimit {
+	limit := SearchLimit{Mode: LimitMovetime, Value: settings.GameMovetimeMS}
+	switch settings.GameSearchMode {
+	case LimitNodes:
+		limit = SearchLimit{Mode: LimitNodes, Value: settings.GameNodes}
+	case LimitDepth:
+		limit = SearchLimit{Mode: LimitDepth, Value: settings.GameDepth}
+	}
+	if limit.Value <= 0 {
+		limit = SearchLimit{Mode: LimitMovetime, Value: 100}
+	}
+	if limit.Mode == LimitMovetime {
+		// per-side overrides give one color time odds
+		if settings.GameWhiteMovetimeMS > 0 {
+			limit.Value = settings.GameWhiteMovetimeMS
+		}
+		black := limit.Value
+		if settings.GameBlackMovetimeMS > 0 {
+			black = settings.GameBlackMovetimeMS
+		}
+		if black != limit.Value {
+			limit.BlackValue = black
+		}
+	}
+	return limit
+}
+
+// assignmentFromQueue turns a queued game into a color assignment. The error
+// names the engine that can't play, missing or ineligible, if any.
+func assignmentFromQueue(entry db.GameQueueEntry, enginesByID map[int64]db.Engine) (ColorAssignment, error) {
+	white, err := queuedEngine(entry.WhiteID, enginesByID)
+	if err != nil {
+		return ColorAssignment{}, err
+	}
+	black, err := queuedEngine(entry.BlackID, enginesByID)
+	if err != nil {
+		return ColorAssignment{}, err
 	}
 	assign := ColorAssignment{
-		White:      white,
-		Black:      black,
-		MovetimeMS: entry.MovetimeMS,
-		BookPath:   entry.BookPath,
+		White:    white,
+		Black:    black,
+		BookPath: entry.BookPath,
 	}
-	if assign.MovetimeMS <= 0 {
-		assign.MovetimeMS = 100
+	if limit, err := ParseSearchLimit(entry.SearchLimit); err == nil {
+		assign.Limit = limit
+	} else {
+		// entries queued before search limits existed only carry a movetime
+		assign.Limit = SearchLimit{Mode: LimitMovetime, Value: entry.MovetimeMS}
+		if assign.Limit.Value <= 0 {
+			assign.Limit.Value = 100
+		}
 	}
 	assign.BookEnabled = strings.TrimSpace(assign.BookPath) != ""
-	return assign, true
+	return assign, nil
+}
+
+func queuedEngine(id int64, enginesByID map[int64]db.Engine) (db.Engine, error) {
+	e, ok := enginesByID[id]
+	if !ok {
+		return db.Engine{}, fmt.Errorf("engine #%d is missing", id)
+	}
+	if reason := IneligibleReason(e); reason != "" {
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", id)
+		}
+		return db.Engine{}, fmt.Errorf("engine %s %s", name, reason)
+	}
+	return e, nil
+}
+
+// sharedProcess reports whether one engine process can play both colors.
+// Only an engine playing itself qualifies. Copies of the same binary with
+// different args or init are separate engines and get a process each, which
+// is what makes same-binary A/B tests work.
+func sharedProcess(a ColorAssignment) bool {
+	return a.White.ID == a.Black.ID
+}
+
+// gameSeed returns the seed for the next game. With a fixed match seed the
+// result only depends on that seed and the game's offset, so a series can be
+// reproduced; otherwise every game gets a fresh seed.
+func gameSeed(matchSeed, offset int64) int64 {
+	if matchSeed != 0 {
+		return matchSeed + offset
+	}
+	return rand.Int63n(math.MaxInt32) + 1
+}
+
+// seededInit substitutes {seed} in an engine's init commands, for engines
+// that take a random seed via setoption.
+func seededInit(init string, seed int64) string {
+	return strings.ReplaceAll(init, "{seed}", strconv.FormatInt(seed, 10))
 }
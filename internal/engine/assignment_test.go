@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"tethys/internal/db"
+)
+
+func testEngines(elos ...float64) []db.Engine {
+	engines := make([]db.Engine, len(elos))
+	for i, elo := range elos {
+		id := int64(i + 1)
+		engines[i] = db.Engine{ID: id, Name: fmt.Sprintf("e%d", id), Path: "/bin/e", Elo: elo}
+	}
+	return engines
+}
+
+// games renders entries as "white-black" for compact comparisons.
+func games(entries []db.GameQueueEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = fmt.Sprintf("%d-%d", e.WhiteID, e.BlackID)
+	}
+	return out
+}
+
+func TestPlanQueue(t *testing.T) {
+	settings := db.Settings{MatchSoftScale: 300, GameMovetimeMS: 100}
+	mirror := settings
+	mirror.MatchAllowMirror = true
+
+	tests := []struct {
+		name     string
+		engines  []db.Engine
+		counts   []db.MatchupCount
+		settings db.Settings
+		want     []string
+	}{
+		{name: "no engines", settings: settings},
+		{name: "single engine", engines: testEngines(3000), settings: settings},
+		{
+			name:     "single engine self-play",
+			engines:  testEngines(3000),
+			settings: mirror,
+			want:     []string{"1-1", "1-1"},
+		},
+		{
+			name:     "single pair alternates colors",
+			engines:  testEngines(3000, 3000),
+			settings: settings,
+			want:     []string{"1-2", "2-1", "1-2", "2-1"},
+		},
+		{
+			name:     "single pair evens out colors",
+			engines:  testEngines(3000, 3000),
+			counts:   []db.MatchupCount{{WhiteID: 1, BlackID: 2, Count: 5}, {WhiteID: 2, BlackID: 1, Count: 1}},
+			settings: settings,
+			want:     []string{"2-1", "2-1", "2-1", "2-1"},
+		},
+		{
+			name:     "small color lead",
+			engines:  testEngines(3000, 3000),
+			counts:   []db.MatchupCount{{WhiteID: 2, BlackID: 1, Count: 3}, {WhiteID: 1, BlackID: 2, Count: 1}},
+			settings: settings,
+			want:     []string{"1-2", "2-1", "1-2", "1-2"},
+		},
+		{
+			name:     "tied pairs go closest first",
+			engines:  testEngines(3100, 3000, 2900),
+			settings: settings,
+			want: []string{
+				"1-2", "2-1", "1-2", "2-1",
+				"2-3", "3-2", "2-3", "3-2",
+				"1-3", "3-1", "1-3", "3-1",
+			},
+		},
+		{
+			name:     "paused engines sit out",
+			engines:  append(testEngines(3000, 3000), db.Engine{ID: 3, Name: "e3", Path: "/bin/e", Elo: 3000, Paused: true}),
+			settings: settings,
+			want:     []string{"1-2", "2-1", "1-2", "2-1"},
+		},
+		{
+			name:     "mirror pairs next to the others",
+			engines:  testEngines(3000, 3000),
+			settings: mirror,
+			want: []string{
+				"1-1", "1-1",
+				"1-2", "2-1", "1-2", "2-1",
+				"2-2", "2-2",
+			},
+		},
+		{
+			// the engine list is sorted by Elo, so the stronger engine 3
+			// comes first; its games against 1 must still be counted
+			name:     "played pairs go last",
+			engines:  []db.Engine{{ID: 3, Name: "e3", Path: "/bin/e", Elo: 3000}, testEngines(3000)[0], {ID: 2, Name: "e2", Path: "/bin/e", Elo: 3000}},
+			counts:   []db.MatchupCount{{WhiteID: 3, BlackID: 1, Count: 5}, {WhiteID: 1, BlackID: 3, Count: 5}},
+			settings: settings,
+			want: []string{
+				"1-2", "2-1", "1-2", "2-1",
+				"2-3", "3-2", "2-3", "3-2",
+				"1-3", "3-1", "1-3", "3-1",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := games(planQueue(tc.engines, tc.counts, tc.settings))
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("planQueue = %v\nwant %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// Playing every planned batch must keep colors even within each pair and
+// eventually cover every pair, however far apart the engines are.
+func TestPlanQueueFairness(t *testing.T) {
+	engines := testEngines(3400, 3200, 3000, 2700, 2000)
+	settings := db.Settings{MatchSoftScale: 300, GameMovetimeMS: 100}
+	played := map[[2]int64]int{}
+	for round := 0; round < 200; round++ {
+		var counts []db.MatchupCount
+		for k, n := range played {
+			counts = append(counts, db.MatchupCount{WhiteID: k[0], BlackID: k[1], Count: n})
+		}
+		entries := planQueue(engines, counts, settings)
+		if len(entries) == 0 {
+			t.Fatal("planQueue returned no games")
+		}
+		for _, e := range entries {
+			played[[2]int64{e.WhiteID, e.BlackID}]++
+		}
+	}
+
+	for i := range engines {
+		for j := i + 1; j < len(engines); j++ {
+			a, b := engines[i].ID, engines[j].ID
+			ab, ba := played[[2]int64{a, b}], played[[2]int64{b, a}]
+			if ab+ba == 0 {
+				t.Errorf("pair %d-%d was never scheduled", a, b)
+			}
+			if ab != ba {
+				t.Errorf("pair %d-%d: %d games as White, %d as Black", a, b, ab, ba)
+			}
+		}
+	}
+}
+
+func TestBalancedWhiteGames(t *testing.T) {
+	tests := []struct{ ab, ba, n, want int }{
+		{0, 0, 4, 2},
+		{5, 1, 4, 0},
+		{1, 3, 4, 3},
+		{0, 10, 4, 4},
+		{2, 2, 1, 0},
+	}
+	for _, tc := range tests {
+		if got := balancedWhiteGames(tc.ab, tc.ba, tc.n); got != tc.want {
+			t.Errorf("balancedWhiteGames(%d, %d, %d) = %d, want %d", tc.ab, tc.ba, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestAssignmentFromQueue(t *testing.T) {
+	engines := testEngines(3000, 3000, 3000)
+	engines[1].Paused = true
+	byID := make(map[int64]db.Engine)
+	for _, e := range engines {
+		byID[e.ID] = e
+	}
+
+	for _, tc := range []struct {
+		white, black int64
+		want         string
+	}{
+		{1, 3, ""},
+		{1, 1, ""},
+		{1, 2, "engine e2 is paused"},
+		{4, 1, "engine #4 is missing"},
+	} {
+		entry := db.GameQueueEntry{WhiteID: tc.white, BlackID: tc.black, SearchLimit: "movetime:100"}
+		assign, err := assignmentFromQueue(entry, byID)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%d vs %d: error %q, want %q", tc.white, tc.black, got, tc.want)
+			continue
+		}
+		if err == nil && (assign.White.ID != tc.white || assign.Black.ID != tc.black) {
+			t.Errorf("%d vs %d: assigned %d vs %d", tc.white, tc.black, assign.White.ID, assign.Black.ID)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchDepth is the fixed search used for engines without a 'bench' command.
+const benchDepth = 12
+
+// BenchResult is the node count and speed reported by a benchmark run.
+type BenchResult struct {
+	Nodes  int64
+	NPS    int64
+	Method string // "bench" or "go depth N"
+}
+
+// Bench measures an engine. It first tries 'bench' on the command line, as
+// understood by Stockfish and most of its derivatives, and falls back to a
+// fixed-depth search of the start position over UCI.
+func Bench(ctx context.Context, path string, args []string) (BenchResult, error) {
+	if res, ok := benchCommand(ctx, path, args); ok {
+		return res, nil
+	}
+	return benchSearch(ctx, path, args)
+}
+
+func benchCommand(ctx context.Context, path string, args []string) (BenchResult, bool) {
+	cmd := exec.CommandContext(ctx, ExpandPath(path), append(append([]string(nil), args...), "bench")...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	// engines that don't know 'bench' start in UCI mode and exit on the
+	// closed stdin
+	out, _ := cmd.CombinedOutput()
+	return parseBenchOutput(string(out))
+}
+
+// parseBenchOutput reads the summary printed at the end of 'bench':
+//
+//	Nodes searched  : 2030154
+//	Nodes/second    : 1431698
+func parseBenchOutput(out string) (BenchResult, bool) {
+	res := BenchResult{Method: "bench"}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Nodes searched":
+			res.Nodes = n
+		case "Nodes/second":
+			res.NPS = n
+		}
+	}
+	return res, res.Nodes > 0
+}
+
+func benchSearch(ctx context.Context, path string, args []string) (BenchResult, error) {
+	eng := NewUCIEngine(path, args)
+	if err := eng.Start(ctx); err != nil {
+		return BenchResult{}, err
+	}
+	defer func() { _ = eng.Close() }()
+	if err := eng.IsReady(ctx); err != nil {
+		return BenchResult{}, err
+	}
+
+	start := time.Now()
+	_, lines, err := eng.BestMove(ctx, nil, SearchLimit{Mode: LimitDepth, Value: benchDepth})
+	if err != nil {
+		return BenchResult{}, err
+	}
+	res := BenchResult{Method: fmt.Sprintf("go depth %d", benchDepth)}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "info" {
+			continue
+		}
+		for i := 1; i+1 < len(fields); i++ {
+			n, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i] {
+			case "nodes":
+				res.Nodes = n
+			case "nps":
+				res.NPS = n
+			}
+		}
+	}
+	if res.Nodes == 0 {
+		return BenchResult{}, fmt.Errorf("engine reported no node count")
+	}
+	if res.NPS == 0 {
+		if ms := time.Since(start).Milliseconds(); ms > 0 {
+			res.NPS = res.Nodes * 1000 / ms
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,21 @@
+package engine
+
+import "testing"
+
+func TestParseBenchOutput(t *testing.T) {
+	out := "Position: 1/50 (rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1)\n" +
+		"info depth 1 seldepth 1 nodes 20 nps 20000\n" +
+		"bestmove e2e4\n" +
+		"===========================\n" +
+		"Total time (ms) : 1418\n" +
+		"Nodes searched  : 2030154\n" +
+		"Nodes/second    : 1431698\n"
+	res, ok := parseBenchOutput(out)
+	if !ok || res.Nodes != 2030154 || res.NPS != 1431698 {
+		t.Fatalf("parseBenchOutput = %+v, %v", res, ok)
+	}
+
+	if _, ok := parseBenchOutput("Unknown command: 'bench'. Type help for more information.\n"); ok {
+		t.Fatal("parseBenchOutput accepted output without a node count")
+	}
+}
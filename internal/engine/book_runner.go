@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"math/rand"
 	"os"
 
 	"github.com/notnil/chess"
@@ -18,12 +19,13 @@ func (r *Runner) bookLine(start *chess.Position, assignment ColorAssignment) []s
 		return nil
 	}
 
+	rng := rand.New(rand.NewSource(assignment.Seed))
 	line := make([]string, 0, 8)
 	pos := start
 	notation := chess.UCINotation{}
 
 	for {
-		move, ok := bookObj.Lookup(pos)
+		move, ok := bookObj.LookupRand(pos, rng)
 		if !ok {
 			break
 		}
@@ -39,6 +41,7 @@ func (r *Runner) bookLine(start *chess.Position, assignment ColorAssignment) []s
 }
 
 func (r *Runner) loadBook(path string) (*book.Book, error) {
+	path = ExpandPath(path)
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -1,18 +1,53 @@
 package engine
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
+// DefaultPublishInterval is the minimum gap between two notifications sent
+// to subscribers; publishes in between are coalesced.
+const DefaultPublishInterval = 100 * time.Millisecond
+
+// DefaultHeartbeat is how often an idle SSE stream gets a comment line so
+// proxies don't drop it.
+const DefaultHeartbeat = 15 * time.Second
+
 type Broadcaster struct {
 	mu   sync.Mutex
 	next int
 	subs map[int]chan struct{}
+
+	interval time.Duration
+	last     time.Time
+	pending  bool // a trailing send is scheduled
+
+	heartbeat time.Duration
+	seq       uint64 // id of the last update sent, see Seq
 }
 
 func NewBroadcaster() *Broadcaster {
-	return &Broadcaster{subs: make(map[int]chan struct{})}
+	return &Broadcaster{subs: make(map[int]chan struct{}), interval: DefaultPublishInterval, heartbeat: DefaultHeartbeat}
+}
+
+// SetHeartbeat changes the SSE keepalive interval for new streams; 0 turns
+// it off.
+func (b *Broadcaster) SetHeartbeat(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.heartbeat = d
+}
+
+// SetMinInterval changes the publish coalescing interval; 0 sends every
+// publish immediately.
+func (b *Broadcaster) SetMinInterval(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.interval = d
 }
 
 func (b *Broadcaster) Subscribe() (id int, ch <-chan struct{}, unsubscribe func()) {
@@ -35,9 +70,39 @@ func (b *Broadcaster) Subscribe() (id int, ch <-chan struct{}, unsubscribe func(
 	}
 }
 
+// Publish notifies subscribers, at most once per interval. A publish that
+// falls inside the interval is delivered when it ends, so the last state
+// (e.g. a finished game) is always sent.
 func (b *Broadcaster) Publish() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	if b.pending {
+		return
+	}
+	wait := b.interval - time.Since(b.last)
+	if b.interval <= 0 || wait <= 0 {
+		b.notifyLocked()
+		return
+	}
+	b.pending = true
+	time.AfterFunc(wait, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.pending = false
+		b.notifyLocked()
+	})
+}
+
+// Seq returns the id of the most recent update sent to subscribers.
+func (b *Broadcaster) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+func (b *Broadcaster) notifyLocked() {
+	b.last = time.Now()
+	b.seq++
 	for _, ch := range b.subs {
 		select {
 		case ch <- struct{}{}:
@@ -61,9 +126,31 @@ func SSEHandler(b *Broadcaster) http.HandlerFunc {
 		_, ch, unsubscribe := b.Subscribe()
 		defer unsubscribe()
 
-		// initial ping
-		_, _ = w.Write([]byte("event: update\ndata: 1\n\n"))
-		flusher.Flush()
+		b.mu.Lock()
+		heartbeat := b.heartbeat
+		b.mu.Unlock()
+		var ticker *time.Ticker
+		var ping <-chan time.Time
+		if heartbeat > 0 {
+			ticker = time.NewTicker(heartbeat)
+			defer ticker.Stop()
+			ping = ticker.C
+		}
+
+		writeUpdate := func() {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: update\ndata: 1\n\n", b.Seq())
+			flusher.Flush()
+		}
+
+		// a client resuming with the current id has nothing to catch up on;
+		// everyone else refetches right away
+		lastID, err := strconv.ParseUint(strings.TrimSpace(r.Header.Get("Last-Event-ID")), 10, 64)
+		if err == nil && lastID == b.Seq() {
+			_, _ = w.Write([]byte(": resumed\n\n"))
+			flusher.Flush()
+		} else {
+			writeUpdate()
+		}
 
 		ctx := r.Context()
 		for {
@@ -71,7 +158,13 @@ func SSEHandler(b *Broadcaster) http.HandlerFunc {
 			case <-ctx.Done():
 				return
 			case <-ch:
-				_, _ = w.Write([]byte("event: update\ndata: 1\n\n"))
+				writeUpdate()
+				if ticker != nil {
+					// only idle streams need the ping
+					ticker.Reset(heartbeat)
+				}
+			case <-ping:
+				_, _ = w.Write([]byte(": ping\n\n"))
 				flusher.Flush()
 			}
 		}
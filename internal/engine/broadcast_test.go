@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublishCoalesces(t *testing.T) {
+	b := NewBroadcaster()
+	b.SetMinInterval(50 * time.Millisecond)
+	_, ch, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	b.Publish()
+	select {
+	case <-ch:
+	default:
+		t.Fatal("first publish was not sent immediately")
+	}
+
+	for i := 0; i < 10; i++ {
+		b.Publish()
+	}
+	select {
+	case <-ch:
+		t.Fatal("publish inside the interval was sent immediately")
+	default:
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("coalesced publish was never sent")
+	}
+	select {
+	case <-ch:
+		t.Fatal("coalesced publishes were sent more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSSEResume(t *testing.T) {
+	b := NewBroadcaster()
+	b.Publish()
+
+	stream := func(lastID string) string {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest("GET", "/api/live/events", nil).WithContext(ctx)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		rec := httptest.NewRecorder()
+		SSEHandler(b)(rec, req)
+		return rec.Body.String()
+	}
+
+	if got, want := stream(""), "id: 1\nevent: update\ndata: 1\n\n"; got != want {
+		t.Fatalf("fresh stream = %q, want %q", got, want)
+	}
+	if got, want := stream("0"), "id: 1\nevent: update\ndata: 1\n\n"; got != want {
+		t.Fatalf("stream behind = %q, want %q", got, want)
+	}
+	if got, want := stream("1"), ": resumed\n\n"; got != want {
+		t.Fatalf("up-to-date stream = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// BuiltinPrefix marks an engine path naming an engine compiled into tethys
+// instead of a binary, e.g. "builtin:random".
+const BuiltinPrefix = "builtin:"
+
+// builtinEngines run a UCI loop over in and out until 'quit' or the end of
+// in.
+var builtinEngines = map[string]func(in io.Reader, out io.Writer){
+	"random": runRandomMover,
+}
+
+// IsBuiltin reports whether path names a built-in engine.
+func IsBuiltin(path string) bool {
+	name, ok := strings.CutPrefix(strings.TrimSpace(path), BuiltinPrefix)
+	if !ok {
+		return false
+	}
+	_, ok = builtinEngines[name]
+	return ok
+}
+
+// runRandomMover plays a uniformly random legal move. It is meant for demos
+// and tests, so it answers 'go' at once whatever the limit. The Seed option
+// makes its moves repeatable; 0, the default, seeds from the clock.
+func runRandomMover(in io.Reader, out io.Writer) {
+	w := bufio.NewWriter(out)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	pos := chess.StartingPosition()
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Fprintln(w, "id name tethys random mover")
+			fmt.Fprintln(w, "id author tethys")
+			fmt.Fprintln(w, "option name Seed type spin default 0 min 0 max 2147483647")
+			fmt.Fprintln(w, "uciok")
+		case "isready":
+			fmt.Fprintln(w, "readyok")
+		case "setoption":
+			if name, value := parseSetOption(fields[1:]); strings.EqualFold(name, "Seed") {
+				if seed, err := strconv.ParseInt(value, 10, 64); err == nil {
+					if seed == 0 {
+						seed = time.Now().UnixNano()
+					}
+					rng = rand.New(rand.NewSource(seed))
+				}
+			}
+		case "ucinewgame":
+			pos = chess.StartingPosition()
+		case "position":
+			if p, ok := parsePosition(fields[1:]); ok {
+				pos = p
+			}
+		case "go":
+			moves := pos.ValidMoves()
+			if len(moves) == 0 {
+				fmt.Fprintln(w, "bestmove (none)")
+				break
+			}
+			mv := chess.UCINotation{}.Encode(pos, moves[rng.Intn(len(moves))])
+			fmt.Fprintf(w, "info depth 1 score cp 0 nodes 1 pv %s\n", mv)
+			fmt.Fprintf(w, "bestmove %s\n", mv)
+		case "quit":
+			_ = w.Flush()
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// parseSetOption reads the arguments of a 'setoption' command. Names and
+// values may contain spaces.
+func parseSetOption(args []string) (name, value string) {
+	if len(args) == 0 || args[0] != "name" {
+		return "", ""
+	}
+	args = args[1:]
+	for i, a := range args {
+		if a == "value" {
+			return strings.Join(args[:i], " "), strings.Join(args[i+1:], " ")
+		}
+	}
+	return strings.Join(args, " "), ""
+}
+
+// parsePosition reads the arguments of a 'position' command.
+func parsePosition(args []string) (*chess.Position, bool) {
+	var pos *chess.Position
+	switch {
+	case len(args) > 0 && args[0] == "startpos":
+		pos = chess.StartingPosition()
+		args = args[1:]
+	case len(args) > 0 && args[0] == "fen":
+		end := len(args)
+		for i, a := range args {
+			if a == "moves" {
+				end = i
+				break
+			}
+		}
+		opt, err := chess.FEN(strings.Join(args[1:end], " "))
+		if err != nil {
+			return nil, false
+		}
+		pos = chess.NewGame(opt).Position()
+		args = args[end:]
+	default:
+		return nil, false
+	}
+	if len(args) == 0 || args[0] != "moves" {
+		return pos, true
+	}
+	notation := chess.UCINotation{}
+	for _, raw := range args[1:] {
+		mv, err := notation.Decode(pos, raw)
+		if err != nil {
+			return nil, false
+		}
+		pos = pos.Update(mv)
+	}
+	return pos, true
+}
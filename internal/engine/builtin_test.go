@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestBuiltinRandomPlaysLegalMoves(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	e := NewUCIEngine("builtin:random", nil)
+	if err := e.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if err := e.NewGame(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	game := chess.NewGame()
+	var moves []string
+	limit := SearchLimit{Mode: LimitMovetime, Value: 10}
+	for len(moves) < 40 && game.Outcome() == chess.NoOutcome {
+		best, lines, err := e.BestMove(ctx, moves, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastPV(lines) != best {
+			t.Errorf("pv %q does not start with bestmove %q", lastPV(lines), best)
+		}
+		mv, err := chess.UCINotation{}.Decode(game.Position(), best)
+		if err != nil {
+			t.Fatalf("ply %d: illegal move %q: %v", len(moves)+1, best, err)
+		}
+		if err := game.Move(mv); err != nil {
+			t.Fatalf("ply %d: %v", len(moves)+1, err)
+		}
+		moves = append(moves, best)
+	}
+}
+
+func TestBuiltinRandomSeedRepeatsMoves(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	play := func(seed string) []string {
+		e := NewUCIEngine("builtin:random", nil)
+		if err := e.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+		if err := e.Send("setoption name Seed value " + seed); err != nil {
+			t.Fatal(err)
+		}
+		var moves []string
+		for len(moves) < 20 {
+			best, _, err := e.BestMove(ctx, moves, SearchLimit{Mode: LimitDepth, Value: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if best == "(none)" {
+				break
+			}
+			moves = append(moves, best)
+		}
+		return moves
+	}
+	first := strings.Join(play("42"), " ")
+	if again := strings.Join(play("42"), " "); again != first {
+		t.Errorf("seed 42 played %q, then %q", first, again)
+	}
+	if other := strings.Join(play("43"), " "); other == first {
+		t.Errorf("seeds 42 and 43 both played %q", first)
+	}
+}
+
+func TestBuiltinRandomFromFEN(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	e := NewUCIEngine("builtin:random", nil)
+	if err := e.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// after Kb1 Black is stalemated
+	best, _, err := e.BestMoveFrom(ctx, "k7/8/1Q6/8/8/8/8/K7 w - - 0 1", []string{"a1b1"}, SearchLimit{Mode: LimitDepth, Value: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best != "(none)" {
+		t.Errorf("bestmove = %q, want (none)", best)
+	}
+}
+
+func TestIsBuiltin(t *testing.T) {
+	for path, want := range map[string]bool{
+		"builtin:random": true,
+		"builtin:nope":   false,
+		"/usr/bin/sf":    false,
+		"":               false,
+	} {
+		if got := IsBuiltin(path); got != want {
+			t.Errorf("IsBuiltin(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if err := NewUCIEngine("builtin:nope", nil).Start(context.Background()); err == nil {
+		t.Error("Start accepted an unknown builtin engine")
+	}
+}
@@ -3,12 +3,27 @@ package engine
 import (
 	"context"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/notnil/chess"
+
+	"tethys/internal/db"
 )
 
-func applyInit(ctx context.Context, e *UCIEngine, init string) error {
+// applyInit sends the engine's init commands. A strengthElo above 0 first
+// caps the engine via UCI_LimitStrength, so init can still override it.
+func applyInit(ctx context.Context, e Engine, init string, strengthElo int) error {
+	if strengthElo > 0 {
+		if err := CheckStrengthOptions(e.Options(), strengthElo); err != nil {
+			return err
+		}
+		for _, cmd := range strengthCommands(strengthElo) {
+			if err := e.Send(cmd); err != nil {
+				return err
+			}
+		}
+	}
 	lines := strings.Split(init, "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -22,6 +37,20 @@ func applyInit(ctx context.Context, e *UCIEngine, init string) error {
 	return e.IsReady(ctx)
 }
 
+// clearHash sends 'setoption name Clear Hash' if the engine offers that
+// button, and reports whether it did.
+func clearHash(ctx context.Context, e Engine) (bool, error) {
+	for _, line := range e.Options() {
+		if name, fields := parseOption(line); name == "Clear Hash" && fields["type"] == "button" {
+			if err := e.Send("setoption name Clear Hash"); err != nil {
+				return false, err
+			}
+			return true, e.IsReady(ctx)
+		}
+	}
+	return false, nil
+}
+
 func outcomeToResult(g *chess.Game) (result, termination string) {
 	out := g.Outcome()
 	method := g.Method()
@@ -36,8 +65,100 @@ func outcomeToResult(g *chess.Game) (result, termination string) {
 	default:
 		result = "*"
 	}
-	termination = method.String()
-	return result, termination
+	return result, db.NormalizeTermination(method.String())
+}
+
+// noMoveOutcome decides a game in which the side to move answered with no
+// move. In a checkmate or stalemate the engine is right and the real result
+// is stored. Otherwise '(none)' counts as a resignation, while the null move
+// '0000' with legal moves on the board is an engine bug and forfeits.
+func noMoveOutcome(pos *chess.Position, best string) (result, termination string) {
+	loss := "0-1"
+	if pos.Turn() == chess.Black {
+		loss = "1-0"
+	}
+	if len(pos.ValidMoves()) == 0 {
+		if pos.Status() == chess.Checkmate {
+			return loss, db.TerminationCheckmate
+		}
+		return "1/2-1/2", db.TerminationStalemate
+	}
+	if best == "0000" {
+		return loss, db.TerminationForfeitNoMove
+	}
+	return loss, db.TerminationResigned(best)
+}
+
+// maxStoredPVMoves bounds the PV kept per ply with game_store_pv.
+const maxStoredPVMoves = 12
+
+// lastPV returns the pv of the last info line before bestmove, cut to
+// maxStoredPVMoves moves, or "" if the engine sent none.
+func lastPV(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 0 || fields[0] != "info" {
+			continue
+		}
+		for j, f := range fields {
+			if f == "string" {
+				// free text to the end of the line
+				break
+			}
+			if f != "pv" {
+				continue
+			}
+			pv := fields[j+1:]
+			if len(pv) > maxStoredPVMoves {
+				pv = pv[:maxStoredPVMoves]
+			}
+			if len(pv) > 0 {
+				return strings.Join(pv, " ")
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// lastScoreCP returns the centipawn score of the last info line that has
+// one. Mate scores and lines without a score don't count.
+func lastScoreCP(lines []string) (int, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 0 || fields[0] != "info" {
+			continue
+		}
+		for j := 1; j+2 < len(fields); j++ {
+			if fields[j] == "string" {
+				break
+			}
+			if fields[j] != "score" {
+				continue
+			}
+			if fields[j+1] != "cp" {
+				return 0, false
+			}
+			cp, err := strconv.Atoi(fields[j+2])
+			return cp, err == nil
+		}
+	}
+	return 0, false
+}
+
+// drawAdjudicated reports whether a game is dead enough to call a draw: at
+// least plies half-moves without capture or pawn move, and both sides' last
+// eval within cp of 0. plies 0 turns the rule off.
+func drawAdjudicated(pos *chess.Position, plies, cp int, evals [2]*int) bool {
+	if plies <= 0 || pos.HalfMoveClock() < plies {
+		return false
+	}
+	for _, eval := range evals {
+		if eval == nil || *eval > cp || *eval < -cp {
+			return false
+		}
+	}
+	return true
 }
 
 func engineDisplayName(path string, fallback string) string {
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/notnil/chess"
+
+	"tethys/internal/db"
+)
+
+func positionFromFEN(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatalf("FEN %q: %v", fen, err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+func TestNoMoveOutcome(t *testing.T) {
+	const (
+		mated     = "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+		stalemate = "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1"
+		blackToGo = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	)
+	start := chess.StartingPosition().String()
+	tests := []struct {
+		name           string
+		fen            string
+		best           string
+		result, reason string
+	}{
+		{"none checkmate", mated, "(none)", "0-1", "Checkmate"},
+		{"none stalemate", stalemate, "(none)", "1/2-1/2", "Stalemate"},
+		{"none with legal moves", start, "(none)", "0-1", "Resigned ((none))"},
+		{"null move checkmate", mated, "0000", "0-1", "Checkmate"},
+		{"null move stalemate", stalemate, "0000", "1/2-1/2", "Stalemate"},
+		{"null move with legal moves", start, "0000", "0-1", "Forfeit: no move"},
+		{"null move by black", blackToGo, "0000", "1-0", "Forfeit: no move"},
+	}
+	for _, tc := range tests {
+		result, reason := noMoveOutcome(positionFromFEN(t, tc.fen), tc.best)
+		if result != tc.result || reason != tc.reason {
+			t.Errorf("%s: noMoveOutcome = %q, %q, want %q, %q", tc.name, result, reason, tc.result, tc.reason)
+		}
+	}
+}
+
+// The runner relies on notnil/chess to end games without mating material,
+// see the Outcome check in Runner.loop.
+func TestInsufficientMaterialEndsGame(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		move  string
+		drawn bool
+	}{
+		{"KvK", "7k/8/8/8/8/8/6p1/6K1 w - - 0 1", "g1g2", true},
+		{"KNvK", "7k/8/8/4p3/8/5N2/8/6K1 w - - 0 1", "f3e5", true},
+		{"KBvKB same color", "5b1k/8/8/6p1/8/8/8/2B3K1 w - - 0 1", "c1g5", true},
+		{"KBvKB opposite color", "4b2k/8/8/6p1/8/8/8/2B3K1 w - - 0 1", "c1g5", false},
+	}
+	for _, tc := range tests {
+		opt, err := chess.FEN(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		game := chess.NewGame(opt)
+		mv, err := chess.UCINotation{}.Decode(game.Position(), tc.move)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if err := game.Move(mv); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		result, termination := outcomeToResult(game)
+		if tc.drawn && (result != "1/2-1/2" || termination != db.TerminationInsufficientMaterial) {
+			t.Errorf("%s: got %q %q, want a draw by insufficient material", tc.name, result, termination)
+		}
+		if !tc.drawn && game.Outcome() != chess.NoOutcome {
+			t.Errorf("%s: game ended with %q %q", tc.name, result, termination)
+		}
+	}
+}
+
+func TestCheckStrengthOptions(t *testing.T) {
+	options := []string{
+		"option name Hash type spin default 16 min 1 max 33554432",
+		"option name UCI_LimitStrength type check default false",
+		"option name UCI_Elo type spin default 1320 min 1320 max 3190",
+	}
+	if err := CheckStrengthOptions(options, 2000); err != nil {
+		t.Errorf("2000: %v", err)
+	}
+	for _, elo := range []int{1000, 3500} {
+		if err := CheckStrengthOptions(options, elo); err == nil {
+			t.Errorf("%d: out of range accepted", elo)
+		}
+	}
+	if err := CheckStrengthOptions(options[:1], 2000); err == nil {
+		t.Error("engine without UCI_Elo accepted")
+	}
+}
+
+func TestLastPV(t *testing.T) {
+	long := "info depth 20 score cp 10 pv e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7 f1e1 b7b5 a4b3"
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"info depth 1 score cp 20 pv e2e4", "info depth 2 score cp 15 pv d2d4 d7d5", "bestmove d2d4"}, "d2d4 d7d5"},
+		// a trailing info line without pv doesn't hide the last one that had it
+		{[]string{"info depth 3 pv g1f3 g8f6", "info nodes 1000 nps 50000", "bestmove g1f3"}, "g1f3 g8f6"},
+		{[]string{"info string pv is off", "bestmove e2e4"}, ""},
+		{[]string{long}, "e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7 f1e1 b7b5"},
+	}
+	for _, tc := range tests {
+		if got := lastPV(tc.lines); got != tc.want {
+			t.Errorf("lastPV(%q) = %q, want %q", tc.lines, got, tc.want)
+		}
+	}
+}
+
+func TestLastScoreCP(t *testing.T) {
+	tests := []struct {
+		lines []string
+		cp    int
+		ok    bool
+	}{
+		{nil, 0, false},
+		{[]string{"info depth 5 score cp -12 pv e7e5", "info nodes 900", "bestmove e7e5"}, -12, true},
+		{[]string{"info depth 5 score cp 40", "info depth 6 score mate 3 pv d1h5"}, 0, false},
+		{[]string{"info depth 7 score cp 5 lowerbound nodes 10"}, 5, true},
+		{[]string{"info string score cp 0"}, 0, false},
+	}
+	for _, tc := range tests {
+		cp, ok := lastScoreCP(tc.lines)
+		if cp != tc.cp || ok != tc.ok {
+			t.Errorf("lastScoreCP(%q) = %d, %v; want %d, %v", tc.lines, cp, ok, tc.cp, tc.ok)
+		}
+	}
+}
+
+func TestDrawAdjudicated(t *testing.T) {
+	// halfmove clock 30
+	pos := positionFromFEN(t, "8/5k2/3r4/8/8/3R4/5K2/8 w - - 30 60")
+	zero, small, big := 0, -8, 35
+	tests := []struct {
+		name  string
+		plies int
+		evals [2]*int
+		want  bool
+	}{
+		{"off", 0, [2]*int{&zero, &zero}, false},
+		{"dead", 20, [2]*int{&zero, &small}, true},
+		{"clock too low", 40, [2]*int{&zero, &zero}, false},
+		{"one side sees an edge", 20, [2]*int{&zero, &big}, false},
+		{"missing eval", 20, [2]*int{&zero, nil}, false},
+	}
+	for _, tc := range tests {
+		if got := drawAdjudicated(pos, tc.plies, 10, tc.evals); got != tc.want {
+			t.Errorf("%s: drawAdjudicated = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// optionsEngine offers some options and records what is sent to it.
+type optionsEngine struct {
+	scriptedEngine
+	options []string
+	sent    []string
+}
+
+func (e *optionsEngine) Options() []string { return e.options }
+
+func (e *optionsEngine) Send(line string) error {
+	e.sent = append(e.sent, line)
+	return nil
+}
+
+func TestClearHash(t *testing.T) {
+	ctx := context.Background()
+	with := &optionsEngine{options: []string{
+		"option name Hash type spin default 16 min 1 max 1024",
+		"option name Clear Hash type button",
+	}}
+	if sent, err := clearHash(ctx, with); err != nil || !sent {
+		t.Fatalf("clearHash = %v, %v; want sent", sent, err)
+	}
+	if len(with.sent) != 1 || with.sent[0] != "setoption name Clear Hash" {
+		t.Errorf("sent %q", with.sent)
+	}
+
+	without := &optionsEngine{options: []string{"option name Hash type spin default 16 min 1 max 1024"}}
+	if sent, err := clearHash(ctx, without); err != nil || sent {
+		t.Fatalf("clearHash without the option = %v, %v", sent, err)
+	}
+	if len(without.sent) != 0 {
+		t.Errorf("sent %q to an engine without Clear Hash", without.sent)
+	}
+}
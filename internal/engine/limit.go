@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	LimitMovetime = "movetime"
+	LimitNodes    = "nodes"
+	LimitDepth    = "depth"
+)
+
+// SearchLimit is the per-move limit passed to the engines via 'go'.
+type SearchLimit struct {
+	Mode  string
+	Value int
+	// BlackValue gives Black a different movetime for time-odds games;
+	// 0 means both sides use Value
+	BlackValue int
+}
+
+// ParseSearchLimit parses the "mode:value" and "movetime:white/black" forms
+// produced by String.
+func ParseSearchLimit(s string) (SearchLimit, error) {
+	mode, raw, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return SearchLimit{}, fmt.Errorf("invalid search limit %q", s)
+	}
+	raw, rawBlack, odds := strings.Cut(raw, "/")
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return SearchLimit{}, fmt.Errorf("invalid search limit %q", s)
+	}
+	limit := SearchLimit{Mode: mode, Value: value}
+	if odds {
+		if limit.BlackValue, err = strconv.Atoi(rawBlack); err != nil || limit.BlackValue <= 0 {
+			return SearchLimit{}, fmt.Errorf("invalid search limit %q", s)
+		}
+	}
+	if err := limit.Validate(); err != nil {
+		return SearchLimit{}, err
+	}
+	return limit, nil
+}
+
+func (l SearchLimit) Validate() error {
+	switch l.Mode {
+	case LimitMovetime, LimitNodes, LimitDepth:
+	default:
+		return fmt.Errorf("unknown search mode %q", l.Mode)
+	}
+	if l.Value <= 0 || l.BlackValue < 0 {
+		return fmt.Errorf("search limit must be positive")
+	}
+	if l.BlackValue != 0 && l.Mode != LimitMovetime {
+		return fmt.Errorf("per-side limits need movetime")
+	}
+	return nil
+}
+
+func (l SearchLimit) String() string {
+	s := l.Mode + ":" + strconv.Itoa(l.Value)
+	if l.BlackValue != 0 && l.BlackValue != l.Value {
+		s += "/" + strconv.Itoa(l.BlackValue)
+	}
+	return s
+}
+
+// ForSide returns the limit for the side to move.
+func (l SearchLimit) ForSide(white bool) SearchLimit {
+	side := SearchLimit{Mode: l.Mode, Value: l.Value}
+	if !white && l.BlackValue != 0 {
+		side.Value = l.BlackValue
+	}
+	return side
+}
+
+// GoCommand returns the UCI 'go' command for this limit.
+func (l SearchLimit) GoCommand() string {
+	return fmt.Sprintf("go %s %d", l.Mode, l.Value)
+}
+
+// MovetimeMS returns the movetime for movetime-limited searches, 0 otherwise.
+// With time odds this is White's.
+func (l SearchLimit) MovetimeMS() int {
+	if l.Mode != LimitMovetime {
+		return 0
+	}
+	return l.Value
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+
+	"tethys/internal/db"
+)
+
+func TestSearchLimitOdds(t *testing.T) {
+	limit := settingsLimit(db.Settings{GameSearchMode: LimitMovetime, GameMovetimeMS: 100, GameBlackMovetimeMS: 300})
+	if got := limit.String(); got != "movetime:100/300" {
+		t.Fatalf("String() = %q, want movetime:100/300", got)
+	}
+	if got := limit.ForSide(true).GoCommand(); got != "go movetime 100" {
+		t.Errorf("white: %q", got)
+	}
+	if got := limit.ForSide(false).GoCommand(); got != "go movetime 300" {
+		t.Errorf("black: %q", got)
+	}
+
+	parsed, err := ParseSearchLimit(limit.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != limit {
+		t.Errorf("round trip = %+v, want %+v", parsed, limit)
+	}
+
+	// equal overrides are an even game
+	even := settingsLimit(db.Settings{GameSearchMode: LimitMovetime, GameMovetimeMS: 100, GameWhiteMovetimeMS: 200, GameBlackMovetimeMS: 200})
+	if got := even.String(); got != "movetime:200" {
+		t.Errorf("even String() = %q, want movetime:200", got)
+	}
+
+	for _, bad := range []string{"nodes:100/200", "movetime:100/0", "movetime:100/x"} {
+		if _, err := ParseSearchLimit(bad); err == nil {
+			t.Errorf("ParseSearchLimit(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandPath expands a leading ~ and $VAR / ${VAR} references in an engine or
+// book path. Stored paths keep what the user typed; callers expand at use.
+func ExpandPath(p string) string {
+	p = os.ExpandEnv(strings.TrimSpace(p))
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
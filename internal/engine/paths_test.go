@@ -0,0 +1,24 @@
+package engine
+
+import "testing"
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	t.Setenv("ENGINES", "/opt/engines")
+	tests := []struct {
+		in, want string
+	}{
+		{"/usr/bin/sf", "/usr/bin/sf"},
+		{"~", "/home/tester"},
+		{"~/engines/sf", "/home/tester/engines/sf"},
+		{"$ENGINES/sf", "/opt/engines/sf"},
+		{"${ENGINES}/sf", "/opt/engines/sf"},
+		{"~other/sf", "~other/sf"},
+		{"./sf", "./sf"},
+	}
+	for _, tt := range tests {
+		if got := ExpandPath(tt.in); got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"tethys/internal/db"
+)
+
+// scriptedEngine answers each search with the next move of its script. An
+// entry of "" fails the search as if the engine died.
+type scriptedEngine struct {
+	script   []string
+	startErr error
+	closed   bool
+}
+
+func (e *scriptedEngine) Start(ctx context.Context) error              { return e.startErr }
+func (e *scriptedEngine) Close() error                                 { e.closed = true; return nil }
+func (e *scriptedEngine) Options() []string                            { return nil }
+func (e *scriptedEngine) Send(line string) error                       { return nil }
+func (e *scriptedEngine) ReadLine(ctx context.Context) (string, error) { return "", io.EOF }
+func (e *scriptedEngine) IsReady(ctx context.Context) error            { return nil }
+func (e *scriptedEngine) NewGame(ctx context.Context) error            { return nil }
+
+func (e *scriptedEngine) BestMoveFrom(ctx context.Context, fen string, movesUCI []string, limit SearchLimit) (string, []string, error) {
+	if len(e.script) == 0 {
+		return "", nil, errors.New("script exhausted")
+	}
+	move := e.script[0]
+	e.script = e.script[1:]
+	if move == "" {
+		return "", []string{"info string crashing"}, io.EOF
+	}
+	return move, []string{"info depth 1 score cp 0 pv " + move, "bestmove " + move}, nil
+}
+
+// newScriptedRunner returns a runner whose engines, looked up by path, are
+// the given scripted ones, plus an assignment of "white" against "black".
+func newScriptedRunner(t *testing.T, engines map[string]*scriptedEngine) (*Runner, ColorAssignment) {
+	t.Helper()
+	store, err := db.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	r := NewRunner(store, NewBroadcaster())
+	r.newEngine = func(path string, args []string) Engine {
+		e, ok := engines[path]
+		if !ok {
+			t.Fatalf("no scripted engine for %q", path)
+		}
+		return e
+	}
+	var assignment ColorAssignment
+	for i, side := range []*db.Engine{&assignment.White, &assignment.Black} {
+		name := []string{"white", "black"}[i]
+		id, err := store.InsertEngine(context.Background(), db.Engine{Name: name, Path: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		*side = db.Engine{ID: id, Name: name, Path: name}
+	}
+	assignment.Limit = SearchLimit{Mode: LimitMovetime, Value: 10}
+	return r, assignment
+}
+
+func playScripted(t *testing.T, white, black *scriptedEngine) (db.GameDetail, bool) {
+	t.Helper()
+	r, assignment := newScriptedRunner(t, map[string]*scriptedEngine{"white": white, "black": black})
+	ctx := context.Background()
+	settings, err := r.store.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.playGame(ctx, assignment, settings)
+	if !white.closed || (black.startErr == nil && !black.closed) {
+		t.Error("engines were not closed after the game")
+	}
+	id := r.Live().GameID
+	if id == 0 {
+		return db.GameDetail{}, false
+	}
+	game, err := r.store.GetGame(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game, true
+}
+
+func TestPlayGameOutcomes(t *testing.T) {
+	tests := []struct {
+		name         string
+		white, black []string
+		result       string
+		termination  string
+		moves        string
+	}{
+		{
+			name:        "checkmate",
+			white:       []string{"f2f3", "g2g4"},
+			black:       []string{"e7e5", "d8h4"},
+			result:      "0-1",
+			termination: "Checkmate",
+			moves:       "f2f3 e7e5 g2g4 d8h4",
+		},
+		{
+			name:        "crash",
+			white:       []string{"e2e4", "g1f3"},
+			black:       []string{"e7e5", ""},
+			result:      "1-0",
+			termination: "Engine crash",
+			moves:       "e2e4 e7e5 g1f3",
+		},
+		{
+			name:        "illegal move",
+			white:       []string{"e2e4", "e1e3"},
+			black:       []string{"e7e5"},
+			result:      "0-1",
+			termination: "Illegal move",
+			moves:       "e2e4 e7e5",
+		},
+		{
+			name:        "resignation",
+			white:       []string{"d2d4"},
+			black:       []string{"(none)"},
+			result:      "1-0",
+			termination: "Resigned ((none))",
+			moves:       "d2d4",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			game, ok := playScripted(t, &scriptedEngine{script: tc.white}, &scriptedEngine{script: tc.black})
+			if !ok {
+				t.Fatal("no game stored")
+			}
+			if game.Result != tc.result || game.Termination != tc.termination {
+				t.Errorf("stored %s (%s), want %s (%s)", game.Result, game.Termination, tc.result, tc.termination)
+			}
+			if game.MovesUCI != tc.moves {
+				t.Errorf("moves = %q, want %q", game.MovesUCI, tc.moves)
+			}
+		})
+	}
+}
+
+func TestPlayGameStartFailureStoresNothing(t *testing.T) {
+	black := &scriptedEngine{startErr: errors.New("exec format error")}
+	if _, ok := playScripted(t, &scriptedEngine{}, black); ok {
+		t.Fatal("a game was stored although black never started")
+	}
+}
+
+func TestPlayGameAbortedDuringStartIsStored(t *testing.T) {
+	white := &scriptedEngine{}
+	black := &scriptedEngine{startErr: context.Canceled}
+	r, assignment := newScriptedRunner(t, map[string]*scriptedEngine{"white": white, "black": black})
+	ctx := context.Background()
+	settings, err := r.store.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.aborted = true
+	r.playGame(ctx, assignment, settings)
+	id := r.Live().GameID
+	if id == 0 {
+		t.Fatal("the aborted game was not stored")
+	}
+	game, err := r.store.GetGame(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Termination != db.TerminationAborted || game.MovesUCI != "" {
+		t.Errorf("stored %q with moves %q, want an Aborted game without moves", game.Termination, game.MovesUCI)
+	}
+}
+
+func TestNextSeedNotReusedAfterDeletes(t *testing.T) {
+	r, assignment := newScriptedRunner(t, map[string]*scriptedEngine{})
+	ctx := context.Background()
+	used := make(map[int64]bool)
+	for want := int64(100); want < 103; want++ {
+		seed, err := r.nextSeed(ctx, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seed != want {
+			t.Fatalf("seed = %d, want %d", seed, want)
+		}
+		used[seed] = true
+		if _, err := r.store.InsertFinishedGame(ctx, assignment.White.ID, assignment.Black.ID, 10, "movetime:10", seed, "", "1-0", db.TerminationCheckmate, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// deleting the games, the latest included, must not hand their seeds out again
+	if n, err := r.store.DeleteResultGames(ctx, "1-0", db.TerminationCheckmate); err != nil || n != 3 {
+		t.Fatalf("deleted %d games (%v), want 3", n, err)
+	}
+	seed, err := r.nextSeed(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used[seed] {
+		t.Errorf("seed %d was used by a deleted game", seed)
+	}
+}
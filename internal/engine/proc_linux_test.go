@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processGone reports whether pid has exited (zombies count as gone, since
+// nothing may reap orphans in a container).
+func processGone(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && (fields[0] == "Z" || fields[0] == "X")
+}
+
+func TestCloseKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := `sleep 60 & echo $! > "$1"
+while read line; do
+	case "$line" in
+	uci) echo uciok ;;
+	quit) exit 0 ;;
+	esac
+done`
+	e := NewUCIEngine("/bin/sh", []string{"-c", script, "sh", pidFile})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("read pid file: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("parse pid: %v", err)
+	}
+
+	_ = e.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !processGone(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child %d still running after Close", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// niceOf reads a process's nice level from /proc.
+func niceOf(t *testing.T, pid int) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	nice, err := strconv.Atoi(fields[16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nice
+}
+
+func TestSetNiceCoversProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := `sleep 60 & echo $! > "$1"
+while read line; do
+	case "$line" in
+	uci) echo uciok ;;
+	quit) exit 0 ;;
+	esac
+done`
+	e := NewUCIEngine("/bin/sh", []string{"-c", script, "sh", pidFile})
+	base := niceOf(t, os.Getpid())
+	e.SetNice(5)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer e.Close()
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("read pid file: %v", err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("parse pid: %v", err)
+	}
+	want := min(base+5, 19)
+	for _, pid := range []int{e.cmd.Process.Pid, child} {
+		if got := niceOf(t, pid); got != want {
+			t.Errorf("process %d runs at nice %d, want %d", pid, got, want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package engine
+
+import "os/exec"
+
+// process groups are unix-only; elsewhere only the engine itself is killed.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// process priorities are left alone outside unix.
+func setNice(cmd *exec.Cmd, nice int) error { return nil }
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package engine
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// start the engine in its own process group so helper processes it spawns
+// can be killed along with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// setNice renices every thread of every process in the engine's group;
+// threads and children started later inherit the level.
+func setNice(cmd *exec.Cmd, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, nice)
+}
+
+// killProcessGroup kills every process in the engine's group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
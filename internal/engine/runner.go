@@ -5,10 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
-	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/notnil/chess"
@@ -20,26 +19,42 @@ import (
 
 type LiveState struct {
 	CreatedAt  string
+	GameID     int64 // set once the game is stored
 	White      string
 	Black      string
+	WhiteID    int64
+	BlackID    int64
+	StartedAt  time.Time
 	MovetimeMS int
+	Limit      string
+	Seed       int64
 	Status     string
 	Result     string
 	MovesUCI   []string
 	BookPlies  int
-	FEN        string
-	Board      [][]SquareView
-	UpdatedAt  time.Time
+	// EvalCP is the last centipawn score reported by the engine that moved,
+	// from White's side; nil before the first one and after mate scores
+	EvalCP    *int
+	FEN       string
+	Board     [][]SquareView
+	UpdatedAt time.Time
 }
 
+// unboundedMoveTimeout caps a single node- or depth-limited search, which
+// (unlike movetime) has no natural deadline.
+const unboundedMoveTimeout = 60 * time.Second
+
 type SquareView struct {
 	Glyph string
 	Class string
 }
 
 type Runner struct {
-	store    *db.Store
-	b        *Broadcaster
+	store *db.Store
+	b     *Broadcaster
+	// starts the engines of a game; tests swap in scripted ones
+	newEngine EngineFactory
+
 	bookMu   sync.Mutex
 	bookPath string
 	bookMod  time.Time
@@ -47,19 +62,70 @@ type Runner struct {
 
 	mu   sync.RWMutex
 	live LiveState
-	stop chan struct{}
+	// deep copy of live handed out by Live until the next setLive
+	snapshot atomic.Pointer[LiveState]
+	stop     chan struct{}
 
 	runningMu sync.Mutex
 	running   bool
+
+	// cancels the game in progress, see AbortBoard
+	gameMu     sync.Mutex
+	cancelGame context.CancelFunc
+	aborted    bool
+
+	skipMu  sync.Mutex
+	skipped []SkippedGame
+}
+
+// SkippedGame is a queued game the runner dropped because one of its engines
+// could not play.
+type SkippedGame struct {
+	At     time.Time
+	White  string
+	Black  string
+	Reason string
+}
+
+// maxSkippedGames is how many skipped games SkippedGames remembers.
+const maxSkippedGames = 20
+
+// SkippedGames returns the most recently skipped queued games, newest first.
+func (r *Runner) SkippedGames() []SkippedGame {
+	r.skipMu.Lock()
+	defer r.skipMu.Unlock()
+	out := make([]SkippedGame, len(r.skipped))
+	for i, s := range r.skipped {
+		out[len(out)-1-i] = s
+	}
+	return out
+}
+
+func (r *Runner) skipGame(entry db.GameQueueEntry, enginesByID map[int64]db.Engine, reason error) {
+	name := func(id int64) string {
+		if e, ok := enginesByID[id]; ok && e.Name != "" {
+			return e.Name
+		}
+		return fmt.Sprintf("#%d", id)
+	}
+	skip := SkippedGame{At: time.Now(), White: name(entry.WhiteID), Black: name(entry.BlackID), Reason: reason.Error()}
+	log.Printf("runner: skipped queued game %s vs %s: %s", skip.White, skip.Black, skip.Reason)
+	r.skipMu.Lock()
+	r.skipped = append(r.skipped, skip)
+	if len(r.skipped) > maxSkippedGames {
+		r.skipped = r.skipped[len(r.skipped)-maxSkippedGames:]
+	}
+	r.skipMu.Unlock()
 }
 
 func NewRunner(store *db.Store, b *Broadcaster) *Runner {
 	start := chess.StartingPosition()
 	r := &Runner{
-		store: store,
-		b:     b,
-		stop:  make(chan struct{}),
-		live:  LiveState{Status: "starting", FEN: start.String(), Board: boardFromPosition(start)},
+		store:     store,
+		b:         b,
+		newEngine: newUCIEngine,
+		stop:      make(chan struct{}),
+		live:      LiveState{Status: "starting", FEN: start.String(), Board: boardFromPosition(start)},
 	}
 	return r
 }
@@ -76,6 +142,19 @@ func (r *Runner) Start(ctx context.Context) {
 	go r.loop(ctx)
 }
 
+// Running reports whether the runner was started and not stopped since.
+func (r *Runner) Running() bool {
+	r.runningMu.Lock()
+	started := r.running
+	r.runningMu.Unlock()
+	select {
+	case <-r.stop:
+		return false
+	default:
+		return started
+	}
+}
+
 func (r *Runner) Stop() {
 	select {
 	case <-r.stop:
@@ -85,7 +164,12 @@ func (r *Runner) Stop() {
 	}
 }
 
+// Live returns a snapshot of the current game. The snapshot is shared between
+// callers until the next update, so its slices must not be modified.
 func (r *Runner) Live() LiveState {
+	if ls := r.snapshot.Load(); ls != nil {
+		return *ls
+	}
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	copyMoves := append([]string(nil), r.live.MovesUCI...)
@@ -96,17 +180,57 @@ func (r *Runner) Live() LiveState {
 	ls := r.live
 	ls.MovesUCI = copyMoves
 	ls.Board = copyBoard
+	// stored under the read lock so a concurrent setLive can't be overwritten
+	// with a stale copy
+	r.snapshot.Store(&ls)
 	return ls
 }
 
+// Boards returns a snapshot of every board the runner plays on. There is
+// only one board for now, but clients should not rely on that.
+func (r *Runner) Boards() []LiveState {
+	return []LiveState{r.Live()}
+}
+
+// AbortBoard cancels the game running on a board. The partial game is stored
+// as "Aborted" and the board moves on to its next assignment.
+func (r *Runner) AbortBoard(index int) error {
+	if index != 0 {
+		return fmt.Errorf("no board %d", index)
+	}
+	r.gameMu.Lock()
+	defer r.gameMu.Unlock()
+	if r.cancelGame == nil || r.Live().Status != "running" {
+		return errors.New("no game running")
+	}
+	r.aborted = true
+	r.cancelGame()
+	return nil
+}
+
+func (r *Runner) abortRequested() bool {
+	r.gameMu.Lock()
+	defer r.gameMu.Unlock()
+	return r.aborted
+}
+
 func (r *Runner) setLive(update func(*LiveState)) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	update(&r.live)
 	r.live.UpdatedAt = time.Now()
+	r.snapshot.Store(nil)
 }
 
 func (r *Runner) loop(parent context.Context) {
+	if r.store != nil {
+		// the runner would otherwise skip these one per loop
+		if n, err := r.store.PruneGameQueue(parent); err != nil {
+			log.Printf("runner: prune queue error: %v", err)
+		} else if n > 0 {
+			log.Printf("runner: dropped %d queued games for missing engines", n)
+		}
+	}
 	for {
 		select {
 		case <-r.stop:
@@ -115,6 +239,10 @@ func (r *Runner) loop(parent context.Context) {
 		}
 
 		ctx, cancel := context.WithCancel(parent)
+		r.gameMu.Lock()
+		r.cancelGame = cancel
+		r.aborted = false
+		r.gameMu.Unlock()
 		func() {
 			defer cancel()
 
@@ -133,38 +261,45 @@ func (r *Runner) loop(parent context.Context) {
 				}
 			}
 
-			engineByID := make(map[int64]db.Engine)
+			// queued games of a paused or deleted engine are dropped as
+			// they come up, see SkippedGames
+			engineByID := make(map[int64]db.Engine, len(engines))
 			for _, e := range engines {
-				if e.ID == 0 || e.Name == "" || e.Path == "" {
-					continue
-				}
 				engineByID[e.ID] = e
 			}
 
 			var assignment ColorAssignment
-			var ok bool
-			if r.store != nil {
-				if entry, hasEntry, err := r.store.DequeueGame(ctx); err != nil {
+			ok := false
+			dequeue := func() {
+				entry, hasEntry, err := r.store.DequeueGame(ctx)
+				if err != nil {
 					log.Printf("runner: dequeue game error: %v", err)
-				} else if hasEntry {
-					assignment, ok = assignmentFromQueue(entry, engineByID)
+					return
+				}
+				if !hasEntry {
+					return
+				}
+				assignment, err = assignmentFromQueue(entry, engineByID)
+				if err != nil {
+					r.skipGame(entry, engineByID, err)
+					return
 				}
+				ok = true
+			}
+			if r.store != nil {
+				dequeue()
 				if !ok {
 					if err := r.fillGameQueue(ctx, settings); err != nil {
 						log.Printf("runner: fill queue error: %v", err)
 					}
-					if entry, hasEntry, err := r.store.DequeueGame(ctx); err != nil {
-						log.Printf("runner: dequeue game error: %v", err)
-					} else if hasEntry {
-						assignment, ok = assignmentFromQueue(entry, engineByID)
-					}
+					dequeue()
 				}
 			}
 
 			if !ok || assignment.White.Path == "" || assignment.Black.Path == "" {
 				start := chess.StartingPosition()
 				message := "waiting for queue"
-				if len(engineByID) < 2 {
+				if len(eligibleEngines(engines)) < 2 {
 					message = "configure engines in /admin"
 				}
 				r.setLive(func(ls *LiveState) {
@@ -177,229 +312,305 @@ func (r *Runner) loop(parent context.Context) {
 				return
 			}
 
+			seed, err := r.nextSeed(ctx, settings.MatchSeed)
+			if err != nil {
+				// guessing the offset could repeat an earlier game's seed
+				log.Printf("runner: game seed error: %v", err)
+				r.failGame(ctx, "*", fmt.Sprintf("game seed error: %v", err))
+				return
+			}
+			assignment.Seed = seed
+
 			whiteDisplay := assignment.White.Name
 			blackDisplay := assignment.Black.Name
 
 			r.setLive(func(ls *LiveState) {
+				ls.GameID = 0
 				ls.White = whiteDisplay
 				ls.Black = blackDisplay
-				ls.MovetimeMS = assignment.MovetimeMS
+				ls.WhiteID = assignment.White.ID
+				ls.BlackID = assignment.Black.ID
+				ls.StartedAt = time.Now()
+				ls.MovetimeMS = assignment.Limit.MovetimeMS()
+				ls.Limit = assignment.Limit.String()
+				ls.Seed = assignment.Seed
 				ls.Status = "running"
 				ls.Result = "*"
 				ls.MovesUCI = nil
 				ls.BookPlies = 0
+				ls.EvalCP = nil
 			})
 			r.b.Publish()
 
-			whiteArgs := strings.Fields(assignment.White.Args)
-			blackArgs := strings.Fields(assignment.Black.Args)
+			r.playGame(ctx, assignment, settings)
+		}()
 
-			white := NewUCIEngine(assignment.White.Path, whiteArgs)
-			selfplay := assignment.White.ID == assignment.Black.ID
-			var black *UCIEngine
-			if selfplay {
-				black = white
-			} else {
-				black = NewUCIEngine(assignment.Black.Path, blackArgs)
-			}
+		// Small pause between games, even on failure.
+		time.Sleep(200 * time.Millisecond)
+	}
+}
 
-			if err := white.Start(ctx); err != nil {
-				r.failGame(ctx, "*", fmt.Sprintf("white start error: %v", err))
-				return
-			}
-			defer func() { _ = white.Close() }()
+// nextSeed returns the seed for the next game. Only a fixed match seed needs
+// an offset, which the store hands out once per game.
+func (r *Runner) nextSeed(ctx context.Context, matchSeed int64) (int64, error) {
+	var offset int64
+	if matchSeed != 0 && r.store != nil {
+		var err error
+		offset, err = r.store.NextSeedOffset(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return gameSeed(matchSeed, offset), nil
+}
 
-			if !selfplay {
-				if err := black.Start(ctx); err != nil {
-					r.failGame(ctx, "*", fmt.Sprintf("black start error: %v", err))
-					return
-				}
-				defer func() { _ = black.Close() }()
-			}
+// playGame plays one assigned game and stores it. Engines that fail to start
+// or initialize only show up in the live state, unless the board was aborted.
+func (r *Runner) playGame(ctx context.Context, assignment ColorAssignment, settings db.Settings) {
+	whiteArgs, err := SplitArgs(assignment.White.Args)
+	if err != nil {
+		r.setupFailed(ctx, assignment, fmt.Sprintf("white args error: %v", err))
+		return
+	}
+	blackArgs, err := SplitArgs(assignment.Black.Args)
+	if err != nil {
+		r.setupFailed(ctx, assignment, fmt.Sprintf("black args error: %v", err))
+		return
+	}
 
-			if err := applyInit(ctx, white, assignment.White.Init); err != nil {
-				r.failGame(ctx, "*", fmt.Sprintf("white init error: %v", err))
-				return
-			}
+	white := r.newEngine(assignment.White.Path, whiteArgs)
+	shared := sharedProcess(assignment)
+	black := white
+	if !shared {
+		black = r.newEngine(assignment.Black.Path, blackArgs)
+	}
 
-			if selfplay {
-				if err := applyInit(ctx, black, assignment.White.Init); err != nil {
-					r.failGame(ctx, "*", fmt.Sprintf("black init error: %v", err))
-					return
-				}
-			} else {
-				if err := applyInit(ctx, black, assignment.Black.Init); err != nil {
-					r.failGame(ctx, "*", fmt.Sprintf("black init error: %v", err))
-					return
-				}
-			}
+	if err := white.Start(ctx); err != nil {
+		r.setupFailed(ctx, assignment, fmt.Sprintf("white start error: %v", err))
+		return
+	}
+	defer func() { _ = white.Close() }()
+
+	if !shared {
+		if err := black.Start(ctx); err != nil {
+			r.setupFailed(ctx, assignment, fmt.Sprintf("black start error: %v", err))
+			return
+		}
+		defer func() { _ = black.Close() }()
+	}
+
+	if err := applyInit(ctx, white, seededInit(assignment.White.Init, assignment.Seed), assignment.White.StrengthElo); err != nil {
+		r.setupFailed(ctx, assignment, fmt.Sprintf("white init error: %v", err))
+		return
+	}
 
-			if err := white.NewGame(ctx); err != nil {
-				r.failGame(ctx, "*", fmt.Sprintf("white newgame error: %v", err))
+	if !shared {
+		if err := applyInit(ctx, black, seededInit(assignment.Black.Init, assignment.Seed), assignment.Black.StrengthElo); err != nil {
+			r.setupFailed(ctx, assignment, fmt.Sprintf("black init error: %v", err))
+			return
+		}
+	}
+
+	if !assignment.White.SkipNewGame {
+		if err := white.NewGame(ctx); err != nil {
+			r.setupFailed(ctx, assignment, fmt.Sprintf("white newgame error: %v", err))
+			return
+		}
+	}
+	if !shared && !assignment.Black.SkipNewGame {
+		if err := black.NewGame(ctx); err != nil {
+			r.setupFailed(ctx, assignment, fmt.Sprintf("black newgame error: %v", err))
+			return
+		}
+	}
+	if settings.GameClearHash {
+		if _, err := clearHash(ctx, white); err != nil {
+			r.setupFailed(ctx, assignment, fmt.Sprintf("white clear hash error: %v", err))
+			return
+		}
+		if !shared {
+			if _, err := clearHash(ctx, black); err != nil {
+				r.setupFailed(ctx, assignment, fmt.Sprintf("black clear hash error: %v", err))
 				return
 			}
-			if !selfplay {
-				if err := black.NewGame(ctx); err != nil {
-					r.failGame(ctx, "*", fmt.Sprintf("black newgame error: %v", err))
-					return
-				}
-			}
+		}
+	}
 
-			game := chess.NewGame()
-			movesUCI := make([]string, 0, 256)
-			bookPlies := 0
-			engineLogs := make([]db.EngineLog, 0, 256)
-
-			bookMoves := r.bookLine(game.Position(), assignment)
-			if len(bookMoves) > 0 {
-				n := chess.UCINotation{}
-				for _, move := range bookMoves {
-					mv, err := n.Decode(game.Position(), move)
-					if err != nil {
-						log.Printf("runner: book move decode error: %v", err)
-						break
-					}
-					if err := game.Move(mv); err != nil {
-						log.Printf("runner: book move apply error: %v", err)
-						break
-					}
-					movesUCI = append(movesUCI, move)
-				}
-				bookPlies = len(movesUCI)
+	game := chess.NewGame()
+	movesUCI := make([]string, 0, 256)
+	bookPlies := 0
+	// with game_fen_interval set, engines get 'position fen baseFEN'
+	// plus the moves played since basePly
+	baseFEN := ""
+	basePly := 0
+	engineLogs := make([]db.EngineLog, 0, 256)
+	// each side's last centipawn eval, for draw adjudication
+	var evals [2]*int
+
+	bookMoves := r.bookLine(game.Position(), assignment)
+	if len(bookMoves) > 0 {
+		n := chess.UCINotation{}
+		for _, move := range bookMoves {
+			mv, err := n.Decode(game.Position(), move)
+			if err != nil {
+				log.Printf("runner: book move decode error: %v", err)
+				break
+			}
+			if err := game.Move(mv); err != nil {
+				log.Printf("runner: book move apply error: %v", err)
+				break
 			}
+			movesUCI = append(movesUCI, move)
+		}
+		bookPlies = len(movesUCI)
+	}
 
-			r.setLive(func(ls *LiveState) {
-				ls.FEN = game.Position().String()
-				ls.Board = boardFromPosition(game.Position())
-				ls.MovesUCI = append([]string(nil), movesUCI...)
-				ls.BookPlies = bookPlies
-			})
-			r.b.Publish()
+	r.setLive(func(ls *LiveState) {
+		ls.FEN = game.Position().String()
+		ls.Board = boardFromPosition(game.Position())
+		ls.MovesUCI = append([]string(nil), movesUCI...)
+		ls.BookPlies = bookPlies
+	})
+	r.b.Publish()
 
-			for {
-				select {
-				case <-r.stop:
-					r.failGame(ctx, "*", "service stopping")
-					return
-				default:
-				}
+	for {
+		select {
+		case <-r.stop:
+			r.failGame(ctx, "*", "service stopping")
+			return
+		default:
+		}
 
-				if len(movesUCI) >= 400 {
-					result := "1/2-1/2"
-					termination := "Max plies"
-					gameID, err := r.store.InsertFinishedGame(ctx, assignment.White.ID, assignment.Black.ID, assignment.MovetimeMS, assignment.BookPath, result, termination, strings.Join(movesUCI, " "), bookPlies)
-					if err != nil {
-						log.Printf("runner: insert game error: %v", err)
-					} else if err := r.store.InsertEngineLogs(ctx, gameID, engineLogs); err != nil {
-						log.Printf("runner: insert engine logs error: %v", err)
-					}
-					r.setLive(func(ls *LiveState) {
-						ls.Status = "finished"
-						ls.Result = result
-					})
-					r.b.Publish()
-					return
-				}
+		if len(movesUCI) >= 400 {
+			r.storeGame(ctx, assignment, "1/2-1/2", db.TerminationMaxPlies, movesUCI, bookPlies, engineLogs)
+			return
+		}
 
-				// Claim draws by 3-fold repetition or 50-move rule (instead of waiting for automatic
-				// 5-fold or 75-move).
-				if game.Outcome() == chess.NoOutcome {
-					for _, method := range game.EligibleDraws() {
-						if method == chess.ThreefoldRepetition || method == chess.FiftyMoveRule {
-							_ = game.Draw(method)
-							break
-						}
-					}
+		// Claim draws by 3-fold repetition or 50-move rule (instead of waiting for automatic
+		// 5-fold or 75-move).
+		if game.Outcome() == chess.NoOutcome {
+			for _, method := range game.EligibleDraws() {
+				if method == chess.ThreefoldRepetition || method == chess.FiftyMoveRule {
+					_ = game.Draw(method)
+					break
 				}
+			}
+		}
 
-				if game.Outcome() != chess.NoOutcome {
-					result, termination := outcomeToResult(game)
-					gameID, err := r.store.InsertFinishedGame(ctx, assignment.White.ID, assignment.Black.ID, assignment.MovetimeMS, assignment.BookPath, result, termination, strings.Join(movesUCI, " "), bookPlies)
-					if err != nil {
-						log.Printf("runner: insert game error: %v", err)
-					} else if err := r.store.InsertEngineLogs(ctx, gameID, engineLogs); err != nil {
-						log.Printf("runner: insert engine logs error: %v", err)
-					}
-					r.setLive(func(ls *LiveState) {
-						ls.Status = "finished"
-						ls.Result = result
-					})
-					r.b.Publish()
-					return
-				}
+		// notnil/chess ends the game itself on checkmate, stalemate,
+		// 5-fold/75-move and insufficient material (KvK, KNvK, KB(s)
+		// vs KB(s) on one color), so engines can't shuffle on to
+		// max plies in a dead draw
+		if game.Outcome() != chess.NoOutcome {
+			result, termination := outcomeToResult(game)
+			r.storeGame(ctx, assignment, result, termination, movesUCI, bookPlies, engineLogs)
+			return
+		}
+		if drawAdjudicated(game.Position(), settings.GameDrawPlies, settings.GameDrawCP, evals) {
+			r.storeGame(ctx, assignment, "1/2-1/2", db.TerminationAdjudicatedDraw, movesUCI, bookPlies, engineLogs)
+			return
+		}
 
-				isWhiteToMove := game.Position().Turn() == chess.White
-				var eng *UCIEngine
-				if isWhiteToMove {
-					eng = white
-				} else {
-					eng = black
-				}
+		isWhiteToMove := game.Position().Turn() == chess.White
+		eng := black
+		if isWhiteToMove {
+			eng = white
+		}
 
-				ply := len(movesUCI) + 1
-				moveTimeoutMS := assignment.MovetimeMS
-				if moveTimeoutMS <= 0 {
-					moveTimeoutMS = 100
-				}
-				if settings.GameSlackMS > 0 {
-					moveTimeoutMS += settings.GameSlackMS
-				}
-				moveCtx, cancelMove := context.WithTimeout(ctx, time.Duration(moveTimeoutMS)*time.Millisecond)
-				start := time.Now()
-				best, logLines, err := eng.BestMoveMovetime(moveCtx, movesUCI, assignment.MovetimeMS)
-				elapsedMS := time.Since(start).Milliseconds()
-				cancelMove()
-				engineID := assignment.White.ID
-				if !isWhiteToMove {
-					engineID = assignment.Black.ID
-				}
-				engineLogs = append(engineLogs, db.EngineLog{
-					Ply:       ply,
-					EngineID:  engineID,
-					ElapsedMS: elapsedMS,
-					Log:       strings.Join(logLines, "\n"),
-				})
-				if err != nil {
-					if errors.Is(err, context.Canceled) {
-						r.failGame(ctx, "*", "service stopping")
-						return
-					}
-					termination := "EngineCrash"
-					if errors.Is(err, context.DeadlineExceeded) {
-						termination = "Timeout"
-					}
-					r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, termination, engineLogs)
-					return
-				}
-				if best == "(none)" || best == "0000" {
-					r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, "NoMove", engineLogs)
-					return
-				}
+		ply := len(movesUCI) + 1
+		limit := assignment.Limit.ForSide(isWhiteToMove)
+		moveTimeout := unboundedMoveTimeout
+		if limit.Mode == LimitMovetime {
+			moveTimeoutMS := limit.Value
+			if settings.GameSlackMS > 0 {
+				moveTimeoutMS += settings.GameSlackMS
+			}
+			moveTimeout = time.Duration(moveTimeoutMS) * time.Millisecond
+		}
+		moveCtx, cancelMove := context.WithTimeout(ctx, moveTimeout)
+		start := time.Now()
+		if settings.GameFENInterval > 0 && len(movesUCI)-basePly >= settings.GameFENInterval {
+			baseFEN = game.Position().String()
+			basePly = len(movesUCI)
+		}
+		best, logLines, err := eng.BestMoveFrom(moveCtx, baseFEN, movesUCI[basePly:], limit)
+		elapsedMS := time.Since(start).Milliseconds()
+		cancelMove()
+		engineID := assignment.White.ID
+		if !isWhiteToMove {
+			engineID = assignment.Black.ID
+		}
+		entry := db.EngineLog{
+			Ply:       ply,
+			EngineID:  engineID,
+			ElapsedMS: elapsedMS,
+			Log:       strings.Join(logLines, "\n"),
+		}
+		if settings.GameStorePV {
+			entry.PV = lastPV(logLines)
+		}
+		engineLogs = append(engineLogs, entry)
+		if err != nil {
+			if r.abortRequested() {
+				// ctx is gone, but the partial game should still be kept
+				r.storeGame(context.Background(), assignment, "", db.TerminationAborted, movesUCI, bookPlies, engineLogs)
+				return
+			}
+			if errors.Is(err, context.Canceled) {
+				r.failGame(ctx, "*", "service stopping")
+				return
+			}
+			termination := db.TerminationEngineCrash
+			if errors.Is(err, context.DeadlineExceeded) {
+				termination = db.TerminationTimeout
+			}
+			r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, termination, engineLogs)
+			return
+		}
+		if best == "(none)" || best == "0000" {
+			result, termination := noMoveOutcome(game.Position(), best)
+			r.storeGame(ctx, assignment, result, termination, movesUCI, bookPlies, engineLogs)
+			return
+		}
 
-				n := chess.UCINotation{}
-				mv, err := n.Decode(game.Position(), best)
-				if err != nil {
-					r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, "IllegalMove", engineLogs)
-					return
-				}
+		n := chess.UCINotation{}
+		mv, err := n.Decode(game.Position(), best)
+		if err != nil {
+			r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, db.TerminationIllegalMove, engineLogs)
+			return
+		}
 
-				if err := game.Move(mv); err != nil {
-					r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, "IllegalMove", engineLogs)
-					return
-				}
+		if err := game.Move(mv); err != nil {
+			r.recordFailedGame(ctx, assignment, isWhiteToMove, movesUCI, bookPlies, db.TerminationIllegalMove, engineLogs)
+			return
+		}
 
-				movesUCI = append(movesUCI, best)
-				r.setLive(func(ls *LiveState) {
-					ls.MovesUCI = append([]string(nil), movesUCI...)
-					ls.FEN = game.Position().String()
-					ls.Board = boardFromPosition(game.Position())
-				})
-				r.b.Publish()
+		side := 0
+		if !isWhiteToMove {
+			side = 1
+		}
+		evals[side] = nil
+		if cp, ok := lastScoreCP(logLines); ok {
+			evals[side] = &cp
+		}
+
+		var whiteCP *int
+		if evals[side] != nil {
+			cp := *evals[side]
+			if side == 1 {
+				cp = -cp
 			}
-		}()
+			whiteCP = &cp
+		}
 
-		// Small pause between games, even on failure.
-		time.Sleep(200 * time.Millisecond)
+		movesUCI = append(movesUCI, best)
+		r.setLive(func(ls *LiveState) {
+			ls.MovesUCI = append([]string(nil), movesUCI...)
+			ls.EvalCP = whiteCP
+			ls.FEN = game.Position().String()
+			ls.Board = boardFromPosition(game.Position())
+		})
+		r.b.Publish()
 	}
 }
 
@@ -411,12 +622,30 @@ func (r *Runner) failGame(ctx context.Context, result, termination string) {
 	r.b.Publish()
 }
 
+// setupFailed ends a game that failed before its first move. An abort is
+// kept as an Aborted game without moves; anything else only shows up in the
+// live state.
+func (r *Runner) setupFailed(ctx context.Context, assignment ColorAssignment, msg string) {
+	if r.abortRequested() {
+		log.Printf("runner: %s vs %s aborted before the first move (%s)", assignment.White.Name, assignment.Black.Name, msg)
+		// ctx is gone, but the abort should still be recorded
+		r.storeGame(context.Background(), assignment, "", db.TerminationAborted, nil, 0, nil)
+		return
+	}
+	r.failGame(ctx, "*", msg)
+}
+
 func (r *Runner) recordFailedGame(ctx context.Context, assignment ColorAssignment, isWhiteToMove bool, movesUCI []string, bookPlies int, termination string, engineLogs []db.EngineLog) {
 	result := "1-0"
 	if isWhiteToMove {
 		result = "0-1"
 	}
-	gameID, err := r.store.InsertFinishedGame(ctx, assignment.White.ID, assignment.Black.ID, assignment.MovetimeMS, assignment.BookPath, result, termination, strings.Join(movesUCI, " "), bookPlies)
+	r.storeGame(ctx, assignment, result, termination, movesUCI, bookPlies, engineLogs)
+}
+
+// storeGame persists a game with its engine logs and marks the board finished.
+func (r *Runner) storeGame(ctx context.Context, assignment ColorAssignment, result, termination string, movesUCI []string, bookPlies int, engineLogs []db.EngineLog) {
+	gameID, err := r.store.InsertFinishedGame(ctx, assignment.White.ID, assignment.Black.ID, assignment.Limit.MovetimeMS(), assignment.Limit.String(), assignment.Seed, assignment.BookPath, result, termination, strings.Join(movesUCI, " "), bookPlies)
 	if err != nil {
 		log.Printf("runner: insert game error: %v", err)
 	} else if err := r.store.InsertEngineLogs(ctx, gameID, engineLogs); err != nil {
@@ -425,6 +654,7 @@ func (r *Runner) recordFailedGame(ctx context.Context, assignment ColorAssignmen
 	r.setLive(func(ls *LiveState) {
 		ls.Status = "finished"
 		ls.Result = result
+		ls.GameID = gameID
 	})
 	r.b.Publish()
 }
@@ -438,7 +668,8 @@ func (r *Runner) fillGameQueue(ctx context.Context, settings db.Settings) error
 	if err != nil {
 		return err
 	}
-	elos := ranking.ComputeBradleyTerryElos(rows, 3600)
+	scoring := ranking.ScoringFromSettings(settings.RankingScoring, settings.RankingDrawScore)
+	elos := ranking.ComputeBradleyTerryElos(rows, 3600, scoring)
 	if err := r.store.ReplaceEngineElos(ctx, elos); err != nil {
 		return err
 	}
@@ -446,115 +677,9 @@ func (r *Runner) fillGameQueue(ctx context.Context, settings db.Settings) error
 	if err != nil {
 		return err
 	}
-	weightedPairs := buildDistanceWeightedPairs(engines, settings.MatchSoftScale, settings.MatchAllowMirror)
-	if len(weightedPairs) == 0 {
-		return nil
-	}
 	counts, err := r.store.ListMatchupCounts(ctx)
 	if err != nil {
 		return err
 	}
-
-	type pairCount struct {
-		AID      int64
-		BID      int64
-		AB       int
-		BA       int
-		Distance float64
-		Weight   float64
-	}
-
-	pairCounts := make(map[[2]int64]*pairCount, len(weightedPairs))
-	for _, pair := range weightedPairs {
-		pairCounts[[2]int64{pair.AID, pair.BID}] = &pairCount{AID: pair.AID, BID: pair.BID, Distance: pair.Distance, Weight: pair.Weight}
-	}
-	for _, c := range counts {
-		a := c.WhiteID
-		b := c.BlackID
-		key := [2]int64{a, b}
-		if a > b {
-			key = [2]int64{b, a}
-			a, b = b, a
-		}
-		pc, ok := pairCounts[key]
-		if !ok {
-			continue
-		}
-		if c.WhiteID == pc.AID && c.BlackID == pc.BID {
-			pc.AB += c.Count
-		} else if c.WhiteID == pc.BID && c.BlackID == pc.AID {
-			pc.BA += c.Count
-		}
-	}
-
-	if len(pairCounts) == 0 {
-		return nil
-	}
-
-	selected := make([]*pairCount, 0, len(pairCounts))
-	for _, pc := range pairCounts {
-		selected = append(selected, pc)
-	}
-	sort.Slice(selected, func(i, j int) bool {
-		totalI := selected[i].AB + selected[i].BA
-		totalJ := selected[j].AB + selected[j].BA
-		scoreI := selected[i].Weight / float64(1+totalI)
-		scoreJ := selected[j].Weight / float64(1+totalJ)
-		if math.Abs(scoreI-scoreJ) > 1e-9 {
-			return scoreI > scoreJ
-		}
-		if totalI != totalJ {
-			return totalI < totalJ
-		}
-		if selected[i].Distance != selected[j].Distance {
-			return selected[i].Distance < selected[j].Distance
-		}
-		if selected[i].AID != selected[j].AID {
-			return selected[i].AID < selected[j].AID
-		}
-		return selected[i].BID < selected[j].BID
-	})
-
-	eligibleCount := len(eligibleEngines(engines))
-	targetPairs := eligibleCount * 2
-	if targetPairs < 4 {
-		targetPairs = 4
-	}
-	if targetPairs > 32 {
-		targetPairs = 32
-	}
-	if targetPairs > len(selected) {
-		targetPairs = len(selected)
-	}
-
-	entries := make([]db.GameQueueEntry, 0, targetPairs*4)
-	for _, pc := range selected[:targetPairs] {
-		if pc.AID == pc.BID {
-			for i := 0; i < 2; i++ {
-				entries = append(entries, db.GameQueueEntry{
-					WhiteID:    pc.AID,
-					BlackID:    pc.BID,
-					MovetimeMS: settings.GameMovetimeMS,
-					BookPath:   settings.GameBookPath,
-				})
-			}
-			continue
-		}
-		for i := 0; i < 2; i++ {
-			entries = append(entries, db.GameQueueEntry{
-				WhiteID:    pc.AID,
-				BlackID:    pc.BID,
-				MovetimeMS: settings.GameMovetimeMS,
-				BookPath:   settings.GameBookPath,
-			})
-			entries = append(entries, db.GameQueueEntry{
-				WhiteID:    pc.BID,
-				BlackID:    pc.AID,
-				MovetimeMS: settings.GameMovetimeMS,
-				BookPath:   settings.GameBookPath,
-			})
-		}
-	}
-
-	return r.store.EnqueueGames(ctx, entries)
+	return r.store.EnqueueGames(ctx, planQueue(engines, counts, settings))
 }
@@ -0,0 +1,23 @@
+package engine
+
+import "testing"
+
+func TestLiveSnapshotInvalidatedBySetLive(t *testing.T) {
+	r := NewRunner(nil, nil)
+	first := r.Live()
+	if again := r.Live(); &again.Board[0][0] != &first.Board[0][0] {
+		t.Fatal("Live rebuilt the snapshot without an update")
+	}
+
+	r.setLive(func(ls *LiveState) {
+		ls.Status = "running"
+		ls.MovesUCI = append(ls.MovesUCI, "e2e4")
+	})
+	got := r.Live()
+	if got.Status != "running" || len(got.MovesUCI) != 1 {
+		t.Fatalf("Live after setLive = %q %v, want running [e2e4]", got.Status, got.MovesUCI)
+	}
+	if len(first.MovesUCI) != 0 {
+		t.Fatalf("earlier snapshot changed: %v", first.MovesUCI)
+	}
+}
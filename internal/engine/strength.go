@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// strengthCommands caps an engine at elo through the standard UCI options.
+func strengthCommands(elo int) []string {
+	return []string{
+		"setoption name UCI_LimitStrength value true",
+		fmt.Sprintf("setoption name UCI_Elo value %d", elo),
+	}
+}
+
+// CheckStrengthOptions reports an error unless the handshake options include
+// UCI_LimitStrength and a UCI_Elo whose range covers elo.
+func CheckStrengthOptions(options []string, elo int) error {
+	var limit, uciElo map[string]string
+	for _, line := range options {
+		name, fields := parseOption(line)
+		switch name {
+		case "UCI_LimitStrength":
+			limit = fields
+		case "UCI_Elo":
+			uciElo = fields
+		}
+	}
+	if limit == nil || uciElo == nil {
+		return fmt.Errorf("engine does not support UCI_LimitStrength/UCI_Elo")
+	}
+	if min, err := strconv.Atoi(uciElo["min"]); err == nil && elo < min {
+		return fmt.Errorf("UCI_Elo %d is below the engine's minimum %d", elo, min)
+	}
+	if max, err := strconv.Atoi(uciElo["max"]); err == nil && elo > max {
+		return fmt.Errorf("UCI_Elo %d is above the engine's maximum %d", elo, max)
+	}
+	return nil
+}
+
+// parseOption splits "option name <name> type <t> default <d> min <m> ..."
+// into the name, which may contain spaces, and the single-word fields after
+// it. 'var' fields repeat, so only the last one is kept.
+func parseOption(line string) (string, map[string]string) {
+	rest, ok := strings.CutPrefix(line, "option name ")
+	if !ok {
+		return "", nil
+	}
+	name, rest, _ := strings.Cut(rest, " type ")
+	fields := map[string]string{}
+	words := strings.Fields("type " + rest)
+	for i := 0; i+1 < len(words); i += 2 {
+		fields[words[i]] = words[i+1]
+	}
+	return strings.TrimSpace(name), fields
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tethys/internal/db"
+)
+
+// StartSuite runs an engine over every position of an imported test suite in
+// the background, replacing the position results of the last run. An
+// engineID of 0 picks the analysis engine. The depth is the analysis depth.
+func (a *Analyzer) StartSuite(ctx context.Context, suite string, engineID int64) error {
+	cfg, err := a.store.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if engineID <= 0 {
+		engineID = cfg.AnalysisEngineID
+	}
+	if engineID <= 0 || cfg.AnalysisDepth <= 0 {
+		return errors.New("analysis engine not configured")
+	}
+	row, err := a.store.EngineByID(ctx, engineID)
+	if err != nil {
+		return fmt.Errorf("engine missing: %w", err)
+	}
+	row = analysisSetup(row, cfg)
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return errors.New("analyzer closed")
+	}
+	if _, running := a.suites[suite]; running {
+		a.mu.Unlock()
+		return fmt.Errorf("suite %s is already running", suite)
+	}
+	jobCtx, cancel := context.WithCancel(context.Background())
+	a.suites[suite] = cancel
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	if err := a.store.ClearSuiteResults(ctx, suite); err != nil {
+		a.endSuite(suite)
+		a.wg.Done()
+		return err
+	}
+	go a.runSuite(jobCtx, suite, row, cfg)
+	return nil
+}
+
+// SuiteRunning reports whether a run of suite is in progress.
+func (a *Analyzer) SuiteRunning(suite string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, running := a.suites[suite]
+	return running
+}
+
+func (a *Analyzer) endSuite(suite string) {
+	a.mu.Lock()
+	if cancel, ok := a.suites[suite]; ok {
+		cancel()
+		delete(a.suites, suite)
+	}
+	a.mu.Unlock()
+}
+
+// runSuite searches the suite's positions one by one with an engine of its
+// own, kept out of the analysis pool so that runs of other engines don't
+// evict it, and records the run once every position is done. Each search
+// still takes one of the analysis job slots.
+func (a *Analyzer) runSuite(ctx context.Context, suite string, row db.Engine, cfg db.Settings) {
+	defer a.wg.Done()
+	defer a.endSuite(suite)
+	depth := cfg.AnalysisDepth
+	positions, err := a.store.SuitePositions(ctx, suite)
+	if err != nil {
+		log.Printf("suite %s: %v", suite, err)
+		return
+	}
+	eng, err := a.startEngine(ctx, row, cfg.AnalysisNice)
+	if err != nil {
+		log.Printf("suite %s: %v", suite, err)
+		return
+	}
+	if err := a.track(eng); err != nil {
+		return
+	}
+	defer a.discard(eng)
+
+	start := time.Now()
+	run := db.SuiteRun{Suite: suite, EngineID: row.ID, Depth: depth}
+	for _, p := range positions {
+		if err := a.acquireSlot(ctx, cfg.AnalysisMaxJobs, nil); err != nil {
+			return
+		}
+		score, reached := "", 0
+		move, err := searchDepth(ctx, eng, p.FEN, depth, func(d int, s, pv string) {
+			score, reached = s, d
+		})
+		a.release()
+		if err != nil {
+			log.Printf("suite %s: position %d: %v", suite, p.ID, err)
+			return
+		}
+		if err := a.store.SetSuiteResult(ctx, p.ID, move, score, row.ID, reached); err != nil {
+			log.Printf("suite %s: %v", suite, err)
+			return
+		}
+		p.FoundMove = move
+		if p.BestMoves != "" {
+			run.Total++
+		}
+		if p.Solved() {
+			run.Solved++
+		}
+	}
+	run.ElapsedMS = time.Since(start).Milliseconds()
+	if err := a.store.InsertSuiteRun(ctx, run); err != nil {
+		log.Printf("suite %s: %v", suite, err)
+	}
+}
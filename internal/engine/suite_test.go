@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tethys/internal/db"
+)
+
+func TestRunSuite(t *testing.T) {
+	store, err := db.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	positions, err := db.ParseEPD(strings.NewReader(
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4; id \"king pawn\";\n" +
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm d4; id \"queen pawn\";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ImportSuite(ctx, "openings", positions); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAnalyzer(store)
+	var starts, closes int
+	a.newEngine = func(path string, args []string) Engine {
+		return &uciStub{starts: &starts, closes: &closes}
+	}
+	if err := a.StartSuite(ctx, "openings", 0); err == nil {
+		t.Fatal("suite ran without an analysis engine")
+	}
+
+	id, err := store.InsertEngine(ctx, db.Engine{Name: "stub", Path: "stub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := store.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.AnalysisEngineID = id
+	if err := store.UpdateSettings(ctx, settings); err != nil {
+		t.Fatal(err)
+	}
+	// with every slot taken the run waits, but not as a queued position
+	for i := 0; i < settings.AnalysisMaxJobs; i++ {
+		if err := a.acquireSlot(ctx, settings.AnalysisMaxJobs, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.StartSuite(ctx, "openings", 0); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.mu.Lock()
+		waiting, latest := len(a.waiting), len(a.latest)
+		a.mu.Unlock()
+		if waiting == 1 {
+			if latest != 0 {
+				t.Errorf("waiting suite run left %d analysis entries", latest)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("suite run never waited for a slot")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < settings.AnalysisMaxJobs; i++ {
+		a.release()
+	}
+	waitSuite(t, a, "openings")
+
+	suites, err := store.ListSuites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 1 || suites[0].Run != 2 || suites[0].Solved != 1 {
+		t.Fatalf("after the run: %+v", suites)
+	}
+	stored, err := store.SuitePositions(ctx, "openings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored[0]; got.FoundMove != "e2e4" || got.FoundScore != "cp 12" || got.Depth != 1 || got.EngineID != id {
+		t.Errorf("first position = %+v", got)
+	}
+	if starts != 1 || closes != 1 {
+		t.Errorf("the run started %d engines and closed %d, want 1 and 1", starts, closes)
+	}
+
+	// a second engine gets its own place on the leaderboard
+	other, err := store.InsertEngine(ctx, db.Engine{Name: "other", Path: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.StartSuite(ctx, "openings", other); err != nil {
+		t.Fatal(err)
+	}
+	waitSuite(t, a, "openings")
+	board, err := store.SuiteLeaderboard(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board) != 2 {
+		t.Fatalf("leaderboard = %+v, want two runs", board)
+	}
+	for _, run := range board {
+		if run.Suite != "openings" || run.Solved != 1 || run.Total != 2 || run.Depth != settings.AnalysisDepth {
+			t.Errorf("run = %+v", run)
+		}
+	}
+	a.Close()
+}
+
+func waitSuite(t *testing.T, a *Analyzer, suite string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for a.SuiteRunning(suite) {
+		if time.Now().After(deadline) {
+			t.Fatal("suite run never finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
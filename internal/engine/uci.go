@@ -3,13 +3,41 @@ package engine
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
+// newGameTimeout is how long NewGame waits for 'readyok' before giving up on it.
+const newGameTimeout = 2 * time.Second
+
+// Engine is a UCI engine as the runner and analyzer drive it. UCIEngine is
+// the real one; tests substitute engines that play scripted moves.
+type Engine interface {
+	Start(ctx context.Context) error
+	Close() error
+	// Options returns the 'option' lines from the handshake.
+	Options() []string
+	Send(line string) error
+	// ReadLine returns the next line of output, giving up when ctx ends.
+	ReadLine(ctx context.Context) (string, error)
+	IsReady(ctx context.Context) error
+	NewGame(ctx context.Context) error
+	BestMoveFrom(ctx context.Context, fen string, movesUCI []string, limit SearchLimit) (string, []string, error)
+}
+
+// EngineFactory returns an unstarted engine for a path and its arguments.
+type EngineFactory func(path string, args []string) Engine
+
+func newUCIEngine(path string, args []string) Engine {
+	return NewUCIEngine(path, args)
+}
+
 type UCIEngine struct {
 	path string
 	args []string
@@ -19,14 +47,45 @@ type UCIEngine struct {
 	out   *bufio.Reader
 	lines chan string
 	errs  chan error
+
+	// raw 'option' lines from the handshake
+	options []string
+
+	// stderr is drained separately; the last line explains a failed start
+	stderrMu   sync.Mutex
+	lastStderr string
+	stderrDone chan struct{}
+
+	// stops a built-in engine's goroutine; nil for processes
+	stopBuiltin func()
+
+	waitOnce sync.Once
+	waitErr  error
+	exited   chan struct{}
+
+	// nice lowers the process priority when above 0
+	nice int
 }
 
 func NewUCIEngine(path string, args []string) *UCIEngine {
 	return &UCIEngine{path: path, args: args}
 }
 
+// SetNice makes Start run the engine, and every thread and helper process
+// it starts, at the given nice level. It does nothing for built-in engines
+// and on platforms without process priorities.
+func (e *UCIEngine) SetNice(nice int) {
+	e.nice = nice
+}
+
 func (e *UCIEngine) Start(ctx context.Context) error {
-	e.cmd = exec.CommandContext(ctx, e.path, e.args...)
+	if name, ok := strings.CutPrefix(e.path, BuiltinPrefix); ok {
+		return e.startBuiltin(ctx, name)
+	}
+	e.cmd = exec.CommandContext(ctx, ExpandPath(e.path), e.args...)
+	setProcessGroup(e.cmd)
+	cmd := e.cmd
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
 	stdout, err := e.cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -40,27 +99,138 @@ func (e *UCIEngine) Start(ctx context.Context) error {
 		return err
 	}
 	e.stdin = stdin
-	e.out = bufio.NewReader(io.MultiReader(stdout, stderr))
+	e.out = bufio.NewReader(stdout)
 	e.lines = make(chan string, 128)
 	e.errs = make(chan error, 1)
+	e.stderrDone = make(chan struct{})
+	e.exited = make(chan struct{})
 
 	if err := e.cmd.Start(); err != nil {
 		return err
 	}
+	if e.nice > 0 {
+		// best effort: the engine still works at normal priority
+		if err := setNice(e.cmd, e.nice); err != nil {
+			log.Printf("engine %s: set nice %d: %v", e.path, e.nice, err)
+		}
+	}
 
 	go e.readLoop()
+	go e.stderrLoop(stderr)
 
-	if err := e.Send("uci"); err != nil {
+	// a write error here means the engine already died; the read below
+	// reports that with its exit code
+	_ = e.Send("uci")
+	if err := e.readHandshake(ctx); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = e.handshakeError()
+		}
+		_ = e.Close()
 		return err
 	}
-	if _, err := e.ReadUntilPrefix(ctx, "uciok", 5*time.Second); err != nil {
-		return err
+
+	return nil
+}
+
+// startBuiltin runs a built-in engine in a goroutine, talking UCI over pipes
+// instead of a process's stdin and stdout.
+func (e *UCIEngine) startBuiltin(ctx context.Context, name string) error {
+	run, ok := builtinEngines[name]
+	if !ok {
+		return fmt.Errorf("unknown builtin engine %q", name)
+	}
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	e.stdin = inW
+	e.out = bufio.NewReader(outR)
+	e.lines = make(chan string, 128)
+	e.errs = make(chan error, 1)
+	done := make(chan struct{})
+	// closing both ends unblocks the engine whether it is reading or writing
+	e.stopBuiltin = func() {
+		_ = inW.Close()
+		_ = outR.Close()
+		<-done
 	}
 
+	go func() {
+		defer close(done)
+		run(inR, outW)
+		_ = outW.Close()
+		_ = inR.Close()
+	}()
+	go e.readLoop()
+
+	_ = e.Send("uci")
+	if err := e.readHandshake(ctx); err != nil {
+		_ = e.Close()
+		return err
+	}
 	return nil
 }
 
+// readHandshake reads up to 'uciok', keeping the options the engine offers.
+func (e *UCIEngine) readHandshake(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for {
+		line, err := e.readLine(ctx)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "option ") {
+			e.options = append(e.options, line)
+		}
+		if strings.HasPrefix(line, "uciok") {
+			return nil
+		}
+	}
+}
+
+// Options returns the 'option' lines the engine sent during the handshake.
+func (e *UCIEngine) Options() []string {
+	return e.options
+}
+
+// handshakeError describes an engine whose output ended before 'uciok',
+// typically a binary for the wrong architecture or with missing libraries.
+func (e *UCIEngine) handshakeError() error {
+	// drain stderr before reaping: Wait closes the pipe and drops what is left
+	select {
+	case <-e.stderrDone:
+	case <-time.After(time.Second):
+	}
+	go e.wait()
+	select {
+	case <-e.exited:
+	case <-time.After(time.Second):
+		return fmt.Errorf("engine closed its output during handshake")
+	}
+	msg := fmt.Sprintf("engine exited during handshake (exit code %d)", e.cmd.ProcessState.ExitCode())
+	e.stderrMu.Lock()
+	last := e.lastStderr
+	e.stderrMu.Unlock()
+	if last != "" {
+		msg += ": " + last
+	}
+	return errors.New(msg)
+}
+
+// wait reaps the process once; Close and handshakeError may both need it.
+func (e *UCIEngine) wait() error {
+	e.waitOnce.Do(func() {
+		e.waitErr = e.cmd.Wait()
+		close(e.exited)
+	})
+	<-e.exited
+	return e.waitErr
+}
+
 func (e *UCIEngine) Close() error {
+	if e.stopBuiltin != nil {
+		e.stopBuiltin()
+		return nil
+	}
 	if e.cmd == nil {
 		return nil
 	}
@@ -70,14 +240,14 @@ func (e *UCIEngine) Close() error {
 	}
 
 	done := make(chan error, 1)
-	go func() { done <- e.cmd.Wait() }()
+	go func() { done <- e.wait() }()
 	select {
 	case err := <-done:
+		// helpers spawned by the engine outlive a clean quit
+		_ = killProcessGroup(e.cmd)
 		return err
 	case <-time.After(2 * time.Second):
-		if e.cmd.Process != nil {
-			_ = e.cmd.Process.Kill()
-		}
+		_ = killProcessGroup(e.cmd)
 		return <-done
 	}
 }
@@ -90,11 +260,11 @@ func (e *UCIEngine) Send(line string) error {
 	return err
 }
 
-func (e *UCIEngine) ReadLine() (string, error) {
+func (e *UCIEngine) ReadLine(ctx context.Context) (string, error) {
 	if e.out == nil {
 		return "", fmt.Errorf("engine not started")
 	}
-	return e.readLine(context.Background())
+	return e.readLine(ctx)
 }
 
 func (e *UCIEngine) ReadUntilPrefix(ctx context.Context, prefix string, timeout time.Duration) (string, error) {
@@ -119,22 +289,41 @@ func (e *UCIEngine) IsReady(ctx context.Context) error {
 	return err
 }
 
+// NewGame sends 'ucinewgame' followed by 'isready'. Some minimal engines never
+// answer after 'ucinewgame', so a missing 'readyok' within a short window is
+// tolerated and the game proceeds anyway. Only a dead engine is an error.
 func (e *UCIEngine) NewGame(ctx context.Context) error {
 	if err := e.Send("ucinewgame"); err != nil {
 		return err
 	}
-	return e.IsReady(ctx)
+	if err := e.Send("isready"); err != nil {
+		return err
+	}
+	_, err := e.ReadUntilPrefix(ctx, "readyok", newGameTimeout)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+func (e *UCIEngine) BestMove(ctx context.Context, movesUCI []string, limit SearchLimit) (string, []string, error) {
+	return e.BestMoveFrom(ctx, "", movesUCI, limit)
 }
 
-func (e *UCIEngine) BestMoveMovetime(ctx context.Context, movesUCI []string, movetimeMS int) (string, []string, error) {
+// BestMoveFrom is BestMove starting from fen instead of the initial position.
+// An empty fen means startpos.
+func (e *UCIEngine) BestMoveFrom(ctx context.Context, fen string, movesUCI []string, limit SearchLimit) (string, []string, error) {
 	pos := "position startpos"
+	if fen != "" {
+		pos = "position fen " + fen
+	}
 	if len(movesUCI) > 0 {
 		pos += " moves " + strings.Join(movesUCI, " ")
 	}
 	if err := e.Send(pos); err != nil {
 		return "", nil, err
 	}
-	if err := e.Send(fmt.Sprintf("go movetime %d", movetimeMS)); err != nil {
+	if err := e.Send(limit.GoCommand()); err != nil {
 		return "", nil, err
 	}
 
@@ -171,6 +360,18 @@ func (e *UCIEngine) readLoop() {
 	}
 }
 
+func (e *UCIEngine) stderrLoop(r io.Reader) {
+	defer close(e.stderrDone)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			e.stderrMu.Lock()
+			e.lastStderr = line
+			e.stderrMu.Unlock()
+		}
+	}
+}
+
 func (e *UCIEngine) readLine(ctx context.Context) (string, error) {
 	select {
 	case line, ok := <-e.lines:
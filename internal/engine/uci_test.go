@@ -0,0 +1,20 @@
+//go:build unix
+
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStartReportsEarlyExit(t *testing.T) {
+	e := NewUCIEngine("/bin/sh", []string{"-c", "echo 'cannot load libfoo.so' >&2; exit 127"})
+	err := e.Start(context.Background())
+	if err == nil {
+		t.Fatal("Start succeeded for an engine that exits immediately")
+	}
+	want := "engine exited during handshake (exit code 127): cannot load libfoo.so"
+	if err.Error() != want {
+		t.Fatalf("Start error = %q, want %q", err, want)
+	}
+}
@@ -2,28 +2,53 @@ package ranking
 
 import (
 	"math"
+	"sort"
 
 	"tethys/internal/db"
 )
 
-func ComputeBradleyTerryElos(rows []db.PairResult, topElo float64) map[int64]float64 {
-	index := make(map[string]int)
-	ids := make([]int64, 0)
+// priorDraws is the number of virtual drawn games added to every pair that
+// has played. Without it an engine that won every game against another runs
+// off to an infinite rating; with it a 10-0 sweep is worth about 530 Elo, and
+// ratings backed by many games barely move.
+const priorDraws = 1.0
+
+// fit stops after maxIterations or once no log-strength moves by more than
+// tolerance in a sweep.
+const (
+	maxIterations = 10000
+	tolerance     = 1e-10
+)
+
+// ComputeBradleyTerryElos fits Bradley-Terry ratings to the pair results. The
+// strongest engine of each connected group of engines gets topElo: engines
+// that never met, even indirectly, cannot be compared, so each group is
+// anchored on its own. Engines without games against others are left out.
+// The result doesn't depend on the order of rows.
+func ComputeBradleyTerryElos(rows []db.PairResult, topElo float64, scoring Scoring) map[int64]float64 {
+	drawFraction := scoring.DrawFraction()
+	idSet := make(map[int64]bool)
 	for _, row := range rows {
-		if _, ok := index[row.EngineA]; !ok {
-			index[row.EngineA] = len(index)
-			ids = append(ids, row.EngineAID)
-		}
-		if _, ok := index[row.EngineB]; !ok {
-			index[row.EngineB] = len(index)
-			ids = append(ids, row.EngineBID)
+		if row.EngineAID == 0 || row.EngineBID == 0 || row.EngineAID == row.EngineBID {
+			continue
 		}
+		idSet[row.EngineAID] = true
+		idSet[row.EngineBID] = true
 	}
-	if len(index) == 0 {
+	if len(idSet) == 0 {
 		return map[int64]float64{}
 	}
+	ids := make([]int64, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	index := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
 
-	n := len(index)
+	n := len(ids)
 	games := make([][]float64, n)
 	wins := make([][]float64, n)
 	for i := 0; i < n; i++ {
@@ -31,99 +56,146 @@ func ComputeBradleyTerryElos(rows []db.PairResult, topElo float64) map[int64]flo
 		wins[i] = make([]float64, n)
 	}
 	for _, row := range rows {
-		i := index[row.EngineA]
-		j := index[row.EngineB]
-		if i == j {
+		i, okA := index[row.EngineAID]
+		j, okB := index[row.EngineBID]
+		if !okA || !okB || i == j {
 			continue
 		}
-		wA := float64(row.WinsA) + 0.5*float64(row.Draws)
-		wB := float64(row.WinsB) + 0.5*float64(row.Draws)
 		nij := float64(row.WinsA + row.WinsB + row.Draws)
+		if nij == 0 {
+			continue
+		}
 		games[i][j] += nij
 		games[j][i] += nij
-		wins[i][j] += wA
-		wins[j][i] += wB
+		wins[i][j] += float64(row.WinsA) + drawFraction*float64(row.Draws)
+		wins[j][i] += float64(row.WinsB) + drawFraction*float64(row.Draws)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && games[i][j] > 0 {
+				wins[i][j] += priorDraws / 2
+			}
+		}
+	}
+	// a draw worth less than half a win is a fraction of a game for the fit,
+	// so count games as the points both sides scored
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && games[i][j] > 0 {
+				games[i][j] = wins[i][j] + wins[j][i]
+			}
+		}
 	}
 
+	// minorization-maximization (Hunter 2004), which converges for any
+	// connected comparison graph once the prior makes every score positive
 	strength := make([]float64, n)
 	for i := range strength {
-		strength[i] = 1.0
+		strength[i] = 1
 	}
-	for iter := 0; iter < 200; iter++ {
-		maxDelta := 0.0
+	prev := make([]float64, n)
+	for iter := 0; iter < maxIterations; iter++ {
+		copy(prev, strength)
 		for i := 0; i < n; i++ {
-			wi := 0.0
-			for j := 0; j < n; j++ {
-				wi += wins[i][j]
-			}
-			if wi == 0 {
-				strength[i] = 0.0
-				continue
-			}
-			denom := 0.0
+			wi, denom := 0.0, 0.0
 			for j := 0; j < n; j++ {
-				if i == j {
-					continue
-				}
-				if games[i][j] == 0 {
+				if i == j || games[i][j] == 0 {
 					continue
 				}
-				sum := strength[i] + strength[j]
-				if sum <= 0 {
-					sum = 1
-				}
-				denom += games[i][j] / sum
-			}
-			if denom == 0 {
-				continue
+				wi += wins[i][j]
+				denom += games[i][j] / (strength[i] + strength[j])
 			}
-			newStrength := wi / denom
-			delta := math.Abs(newStrength - strength[i])
-			if delta > maxDelta {
-				maxDelta = delta
+			if denom > 0 {
+				strength[i] = wi / denom
 			}
-			strength[i] = newStrength
 		}
-		if maxDelta < 1e-6 {
+		// only ratios matter; rescaling keeps the values from drifting
+		maxStrength := 0.0
+		for _, s := range strength {
+			maxStrength = math.Max(maxStrength, s)
+		}
+		maxDelta := 0.0
+		for i := range strength {
+			strength[i] /= maxStrength
+			maxDelta = math.Max(maxDelta, math.Abs(math.Log(strength[i]/prev[i])))
+		}
+		if maxDelta < tolerance {
 			break
 		}
 	}
 
-	maxStrength := 0.0
-	for _, s := range strength {
-		if s > maxStrength {
-			maxStrength = s
+	// anchor each connected group on its strongest engine
+	group := components(games)
+	groupMax := make(map[int]float64)
+	for i, g := range group {
+		if strength[i] > groupMax[g] {
+			groupMax[g] = strength[i]
 		}
 	}
-	if maxStrength == 0 {
-		maxStrength = 1
-	}
-	minStrength := maxStrength * 1e-6
-	if minStrength <= 0 {
-		minStrength = 1e-6
+	elos := make(map[int64]float64, n)
+	for i, id := range ids {
+		elos[id] = topElo + 400*math.Log10(strength[i]/groupMax[group[i]])
 	}
+	return elos
+}
 
-	elos := make(map[int64]float64, n)
-	for i := 0; i < n; i++ {
-		id := ids[i]
-		if id == 0 {
+// components labels each engine with the connected group it belongs to in
+// the graph of pairs that played.
+func components(games [][]float64) []int {
+	group := make([]int, len(games))
+	for i := range group {
+		group[i] = -1
+	}
+	next := 0
+	for start := range games {
+		if group[start] >= 0 {
 			continue
 		}
-		totalGames := 0.0
-		for j := 0; j < n; j++ {
-			if i == j {
-				continue
+		group[start] = next
+		stack := []int{start}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for j, g := range games[i] {
+				if g > 0 && group[j] < 0 {
+					group[j] = next
+					stack = append(stack, j)
+				}
 			}
-			totalGames += games[i][j]
 		}
-		if totalGames == 0 {
+		next++
+	}
+	return group
+}
+
+// AverageOpponentElos returns the games-weighted mean Elo of each engine's
+// opponents, to put a score percentage into context. Self-play and
+// opponents missing from elos are left out.
+func AverageOpponentElos(rows []db.PairResult, elos map[int64]float64) map[int64]float64 {
+	sum := make(map[int64]float64)
+	games := make(map[int64]float64)
+	add := func(id, opp int64, n float64) {
+		elo, ok := elos[opp]
+		if !ok {
+			return
+		}
+		sum[id] += n * elo
+		games[id] += n
+	}
+	for _, row := range rows {
+		if row.EngineAID == row.EngineBID {
 			continue
 		}
-		s := strength[i]
-		if s < minStrength {
-			s = minStrength
+		n := float64(row.WinsA + row.WinsB + row.Draws)
+		if n == 0 {
+			continue
 		}
-		elos[id] = topElo + 400*math.Log10(s/maxStrength)
+		add(row.EngineAID, row.EngineBID, n)
+		add(row.EngineBID, row.EngineAID, n)
 	}
-	return elos
+	out := make(map[int64]float64, len(sum))
+	for id, total := range sum {
+		out[id] = total / games[id]
+	}
+	return out
 }
@@ -0,0 +1,106 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+
+	"tethys/internal/db"
+)
+
+func pair(a, b int64, winsA, winsB, draws int) db.PairResult {
+	return db.PairResult{EngineAID: a, EngineBID: b, WinsA: winsA, WinsB: winsB, Draws: draws}
+}
+
+func assertElo(t *testing.T, elos map[int64]float64, id int64, want float64) {
+	t.Helper()
+	got, ok := elos[id]
+	if !ok {
+		t.Fatalf("engine %d has no rating", id)
+	}
+	if math.IsNaN(got) || math.Abs(got-want) > 0.01 {
+		t.Errorf("engine %d: Elo %.2f, want %.2f", id, got, want)
+	}
+}
+
+func TestBradleyTerryTwoEngines(t *testing.T) {
+	// with the prior draw the fit is 3.5 : 1.5, 400*log10(7/3) apart
+	elos := ComputeBradleyTerryElos([]db.PairResult{pair(1, 2, 3, 1, 0)}, 3000, StandardScoring)
+	assertElo(t, elos, 1, 3000)
+	assertElo(t, elos, 2, 3000-147.19)
+
+	// draws count half, so this is the same 3.5 : 1.5
+	elos = ComputeBradleyTerryElos([]db.PairResult{pair(1, 2, 2, 0, 2)}, 3000, StandardScoring)
+	assertElo(t, elos, 2, 3000-147.19)
+}
+
+func TestBradleyTerryEven(t *testing.T) {
+	rows := []db.PairResult{pair(1, 2, 5, 5, 10), pair(2, 3, 0, 0, 7), pair(3, 1, 4, 4, 0)}
+	elos := ComputeBradleyTerryElos(rows, 3000, StandardScoring)
+	for id := int64(1); id <= 3; id++ {
+		assertElo(t, elos, id, 3000)
+	}
+}
+
+func TestBradleyTerryTransitiveSweep(t *testing.T) {
+	// 1 beats 2 beats 3, every game; the ratings must stay finite and ordered
+	rows := []db.PairResult{pair(1, 2, 10, 0, 0), pair(2, 3, 10, 0, 0), pair(1, 3, 10, 0, 0)}
+	elos := ComputeBradleyTerryElos(rows, 3000, StandardScoring)
+	assertElo(t, elos, 1, 3000)
+	gap12 := elos[1] - elos[2]
+	gap23 := elos[2] - elos[3]
+	if gap12 < 300 || gap12 > 1000 || math.Abs(gap12-gap23) > 0.01 {
+		t.Errorf("gaps %.1f and %.1f, want equal and between 300 and 1000", gap12, gap23)
+	}
+}
+
+func TestBradleyTerryDisconnected(t *testing.T) {
+	// {1, 2} and {3, 4} never met: each group is anchored on its own
+	rows := []db.PairResult{pair(1, 2, 3, 1, 0), pair(4, 3, 6, 2, 2)}
+	elos := ComputeBradleyTerryElos(rows, 3000, StandardScoring)
+	assertElo(t, elos, 1, 3000)
+	assertElo(t, elos, 2, 3000-147.19)
+	assertElo(t, elos, 4, 3000)
+	// 7.5 : 3.5 with the prior
+	assertElo(t, elos, 3, 3000-400*math.Log10(7.5/3.5))
+}
+
+func TestBradleyTerryIgnoresOrderAndSelfPlay(t *testing.T) {
+	rows := []db.PairResult{pair(1, 2, 7, 3, 2), pair(2, 3, 4, 4, 6), pair(3, 1, 1, 5, 3), pair(2, 2, 3, 3, 0)}
+	reversed := []db.PairResult{rows[3], rows[2], rows[1], rows[0]}
+	a := ComputeBradleyTerryElos(rows, 3000, StandardScoring)
+	b := ComputeBradleyTerryElos(reversed, 3000, StandardScoring)
+	if len(a) != 3 {
+		t.Fatalf("rated %d engines, want 3", len(a))
+	}
+	for id, elo := range a {
+		assertElo(t, b, id, elo)
+	}
+
+	only := ComputeBradleyTerryElos([]db.PairResult{pair(5, 5, 1, 1, 1)}, 3000, StandardScoring)
+	if len(only) != 0 {
+		t.Errorf("self-play alone rated %v", only)
+	}
+}
+
+func TestBradleyTerryDrawScoring(t *testing.T) {
+	// a draw is worth a third of a win under football scoring, so the fit is
+	// 1 + 10/3 + 0.5 against 10/3 + 0.5 with the prior
+	rows := []db.PairResult{pair(1, 2, 1, 0, 10)}
+	elos := ComputeBradleyTerryElos(rows, 3000, FootballScoring)
+	assertElo(t, elos, 1, 3000)
+	assertElo(t, elos, 2, 3000-400*math.Log10((1+10.0/3+0.5)/(10.0/3+0.5)))
+}
+
+func TestAverageOpponentElos(t *testing.T) {
+	rows := []db.PairResult{pair(1, 2, 3, 1, 0), pair(3, 1, 0, 0, 12), pair(1, 1, 5, 5, 0), pair(2, 4, 1, 0, 0)}
+	elos := map[int64]float64{1: 3000, 2: 2800, 3: 3200}
+	avg := AverageOpponentElos(rows, elos)
+	// 4 games against 2800 and 12 against 3200; self-play doesn't count
+	assertElo(t, avg, 1, (4*2800+12*3200)/16.0)
+	// engine 4 has no rating, so engine 2 only faced engine 1
+	assertElo(t, avg, 2, 3000)
+	assertElo(t, avg, 4, 2800)
+	if len(avg) != 4 {
+		t.Errorf("got averages for %d engines, want 4", len(avg))
+	}
+}
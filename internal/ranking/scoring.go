@@ -0,0 +1,42 @@
+package ranking
+
+// Scoring assigns points to a win, draw and loss.
+type Scoring struct {
+	Win  float64
+	Draw float64
+	Loss float64
+}
+
+var (
+	StandardScoring = Scoring{Win: 1, Draw: 0.5, Loss: 0}
+	FootballScoring = Scoring{Win: 3, Draw: 1, Loss: 0}
+)
+
+// ScoringFromSettings maps the ranking_scoring setting to a Scoring. "custom"
+// uses drawScore with a win worth 1; anything unknown falls back to standard.
+func ScoringFromSettings(name string, drawScore float64) Scoring {
+	switch name {
+	case "football":
+		return FootballScoring
+	case "custom":
+		if drawScore < 0 || drawScore > 1 {
+			return StandardScoring
+		}
+		return Scoring{Win: 1, Draw: drawScore, Loss: 0}
+	default:
+		return StandardScoring
+	}
+}
+
+// DrawFraction is the share of a win a draw is worth, as used by the rating
+// fit (0.5 for standard scoring, 1/3 for football).
+func (s Scoring) DrawFraction() float64 {
+	if s.Win <= s.Loss {
+		return 0.5
+	}
+	return (s.Draw - s.Loss) / (s.Win - s.Loss)
+}
+
+func (s Scoring) Points(wins, draws, losses int) float64 {
+	return s.Win*float64(wins) + s.Draw*float64(draws) + s.Loss*float64(losses)
+}
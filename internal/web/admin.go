@@ -2,6 +2,8 @@ package web
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -43,15 +45,26 @@ func (h *Handler) handleAdminSettings(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimSpace(cfg.GameBookPath) != "" {
 		bookName = filepath.Base(cfg.GameBookPath)
 	}
-	_ = h.tpl.ExecuteTemplate(w, "global_settings.html", map[string]any{
-		"Cfg":      cfg,
-		"Engines":  engines,
-		"Books":    books,
-		"BookName": bookName,
-		"Page":     "settings",
+	h.render(w, "global_settings.html", map[string]any{
+		"Cfg":                   cfg,
+		"Engines":               engines,
+		"Books":                 books,
+		"BookName":              bookName,
+		"AnalysisEngineMissing": h.analysisEngineMissing(r.Context(), cfg),
+		"Page":                  "settings",
 	})
 }
 
+// analysisEngineMissing reports a configured analysis engine that has since
+// been deleted; lookup errors are not reported.
+func (h *Handler) analysisEngineMissing(ctx context.Context, cfg db.Settings) bool {
+	if cfg.AnalysisEngineID == 0 {
+		return false
+	}
+	ok, err := h.store.EngineExists(ctx, cfg.AnalysisEngineID)
+	return err == nil && !ok
+}
+
 func (h *Handler) handleAdminSettingsSave(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -77,9 +90,65 @@ func (h *Handler) handleAdminSettingsSave(w http.ResponseWriter, r *http.Request
 			analysisDepth = 12
 		}
 	}
+	analysisMaxJobs := cfg.AnalysisMaxJobs
+	if raw := strings.TrimSpace(r.Form.Get("analysis_max_jobs")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			http.Error(w, "invalid analysis job limit", http.StatusBadRequest)
+			return
+		}
+		analysisMaxJobs = v
+	}
+	// 0 leaves the engine's own Threads and Hash alone
+	analysisThreads := cfg.AnalysisThreads
+	if raw := strings.TrimSpace(r.Form.Get("analysis_threads")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid analysis threads", http.StatusBadRequest)
+			return
+		}
+		analysisThreads = v
+	}
+	analysisHashMB := cfg.AnalysisHashMB
+	if raw := strings.TrimSpace(r.Form.Get("analysis_hash_mb")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid analysis hash size", http.StatusBadRequest)
+			return
+		}
+		analysisHashMB = v
+	}
+	// raising priority needs privileges, so only 0 (off) to 19 are offered
+	analysisNice := cfg.AnalysisNice
+	if raw := strings.TrimSpace(r.Form.Get("analysis_nice")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > 19 {
+			http.Error(w, "analysis nice must be between 0 and 19", http.StatusBadRequest)
+			return
+		}
+		analysisNice = v
+	}
 	analysisEngineID := cfg.AnalysisEngineID
-	if raw := strings.TrimSpace(r.Form.Get("analysis_engine_id")); raw != "" {
-		analysisEngineID, _ = strconv.ParseInt(raw, 10, 64)
+	if _, ok := r.Form["analysis_engine_id"]; ok {
+		// "(none)" submits an empty value and turns the analyzer off
+		analysisEngineID = 0
+		if raw := strings.TrimSpace(r.Form.Get("analysis_engine_id")); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid analysis engine", http.StatusBadRequest)
+				return
+			}
+			if id != 0 {
+				if _, err := h.store.EngineByID(r.Context(), id); errors.Is(err, sql.ErrNoRows) {
+					http.Error(w, "unknown analysis engine", http.StatusBadRequest)
+					return
+				} else if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			analysisEngineID = id
+		}
 	}
 	gameMovetime, _ := strconv.Atoi(strings.TrimSpace(r.Form.Get("game_movetime_ms")))
 	if gameMovetime <= 0 {
@@ -88,6 +157,46 @@ func (h *Handler) handleAdminSettingsSave(w http.ResponseWriter, r *http.Request
 			gameMovetime = 100
 		}
 	}
+	gameWhiteMovetime := cfg.GameWhiteMovetimeMS
+	if raw, ok := r.Form["game_white_movetime_ms"]; ok {
+		v, err := optionalMovetime(raw[0])
+		if err != nil {
+			http.Error(w, "invalid white movetime", http.StatusBadRequest)
+			return
+		}
+		gameWhiteMovetime = v
+	}
+	gameBlackMovetime := cfg.GameBlackMovetimeMS
+	if raw, ok := r.Form["game_black_movetime_ms"]; ok {
+		v, err := optionalMovetime(raw[0])
+		if err != nil {
+			http.Error(w, "invalid black movetime", http.StatusBadRequest)
+			return
+		}
+		gameBlackMovetime = v
+	}
+	gameSearchMode := cfg.GameSearchMode
+	if raw := strings.TrimSpace(r.Form.Get("game_search_mode")); raw != "" {
+		if (engine.SearchLimit{Mode: raw, Value: 1}).Validate() != nil {
+			http.Error(w, "invalid search mode", http.StatusBadRequest)
+			return
+		}
+		gameSearchMode = raw
+	}
+	gameNodes, _ := strconv.Atoi(strings.TrimSpace(r.Form.Get("game_nodes")))
+	if gameNodes <= 0 {
+		gameNodes = cfg.GameNodes
+		if gameNodes <= 0 {
+			gameNodes = 1000000
+		}
+	}
+	gameDepth, _ := strconv.Atoi(strings.TrimSpace(r.Form.Get("game_depth")))
+	if gameDepth <= 0 {
+		gameDepth = cfg.GameDepth
+		if gameDepth <= 0 {
+			gameDepth = 10
+		}
+	}
 	gameSlack, _ := strconv.Atoi(strings.TrimSpace(r.Form.Get("game_slack_ms")))
 	if gameSlack <= 0 {
 		gameSlack = cfg.GameSlackMS
@@ -102,10 +211,72 @@ func (h *Handler) handleAdminSettingsSave(w http.ResponseWriter, r *http.Request
 			matchSoftScale = 300
 		}
 	}
+	gameFENInterval := cfg.GameFENInterval
+	if raw := strings.TrimSpace(r.Form.Get("game_fen_interval")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid fen interval", http.StatusBadRequest)
+			return
+		}
+		gameFENInterval = v
+	}
+	gameDrawPlies := cfg.GameDrawPlies
+	if raw := strings.TrimSpace(r.Form.Get("game_draw_plies")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid draw adjudication plies", http.StatusBadRequest)
+			return
+		}
+		gameDrawPlies = v
+	}
+	gameDrawCP := cfg.GameDrawCP
+	if raw := strings.TrimSpace(r.Form.Get("game_draw_cp")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid draw adjudication eval window", http.StatusBadRequest)
+			return
+		}
+		gameDrawCP = v
+	}
+	matchSeed := cfg.MatchSeed
+	if raw := strings.TrimSpace(r.Form.Get("match_seed")); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid match seed", http.StatusBadRequest)
+			return
+		}
+		matchSeed = v
+	}
+	rankingScoring := cfg.RankingScoring
+	if raw := strings.TrimSpace(r.Form.Get("ranking_scoring")); raw != "" {
+		switch raw {
+		case "standard", "football", "custom":
+			rankingScoring = raw
+		default:
+			http.Error(w, "invalid ranking scoring", http.StatusBadRequest)
+			return
+		}
+	}
+	rankingDrawScore := cfg.RankingDrawScore
+	if raw := strings.TrimSpace(r.Form.Get("ranking_draw_score")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			http.Error(w, "invalid draw score (0 to 1)", http.StatusBadRequest)
+			return
+		}
+		rankingDrawScore = v
+	}
+	gameStorePV := cfg.GameStorePV
+	if _, ok := r.Form["game_store_pv"]; ok {
+		gameStorePV = checkboxValue(r.Form.Get("game_store_pv"))
+	}
+	gameClearHash := cfg.GameClearHash
+	if _, ok := r.Form["game_clear_hash"]; ok {
+		gameClearHash = checkboxValue(r.Form.Get("game_clear_hash"))
+	}
 	matchAllowMirror := cfg.MatchAllowMirror
 	if _, ok := r.Form["match_allow_mirror"]; ok {
-		raw := strings.TrimSpace(r.Form.Get("match_allow_mirror"))
-		matchAllowMirror = raw == "1" || strings.EqualFold(raw, "true") || strings.EqualFold(raw, "on")
+		matchAllowMirror = checkboxValue(r.Form.Get("match_allow_mirror"))
 	}
 	gameBook := ""
 	if vals, ok := r.Form["game_book"]; ok {
@@ -139,22 +310,56 @@ func (h *Handler) handleAdminSettingsSave(w http.ResponseWriter, r *http.Request
 		gameBookPath = filepath.Join(h.booksDir, gameBook)
 	}
 
+	before := cfg
 	cfg.OpeningMin = openingMin
 	cfg.AnalysisDepth = analysisDepth
+	cfg.AnalysisMaxJobs = analysisMaxJobs
+	cfg.AnalysisThreads = analysisThreads
+	cfg.AnalysisHashMB = analysisHashMB
+	cfg.AnalysisNice = analysisNice
 	cfg.AnalysisEngineID = analysisEngineID
 	cfg.GameMovetimeMS = gameMovetime
+	cfg.GameWhiteMovetimeMS = gameWhiteMovetime
+	cfg.GameBlackMovetimeMS = gameBlackMovetime
+	cfg.GameSearchMode = gameSearchMode
+	cfg.GameNodes = gameNodes
+	cfg.GameDepth = gameDepth
 	cfg.GameSlackMS = gameSlack
+	cfg.GameFENInterval = gameFENInterval
 	cfg.GameBookPath = gameBookPath
 	cfg.MatchSoftScale = matchSoftScale
 	cfg.MatchAllowMirror = matchAllowMirror
+	cfg.GameStorePV = gameStorePV
+	cfg.GameClearHash = gameClearHash
+	cfg.GameDrawPlies = gameDrawPlies
+	cfg.GameDrawCP = gameDrawCP
+	cfg.MatchSeed = matchSeed
+	cfg.RankingScoring = rankingScoring
+	cfg.RankingDrawScore = rankingDrawScore
 
-	if err := h.store.UpdateSettings(r.Context(), cfg); err != nil {
+	if err := h.store.UpdateChangedSettings(r.Context(), before, cfg); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if changes := fieldChanges(before, cfg); changes != "" {
+		h.audit(r, "settings", changes)
+	}
 	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
 }
 
+// parse a per-side movetime override; empty or 0 means none
+func optionalMovetime(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid movetime %q", raw)
+	}
+	return v, nil
+}
+
 func (h *Handler) handleAdminMatches(w http.ResponseWriter, r *http.Request) {
 	cfg, err := h.store.GetSettings(r.Context())
 	if err != nil {
@@ -170,14 +375,156 @@ func (h *Handler) handleAdminMatches(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimSpace(cfg.GameBookPath) != "" {
 		bookName = filepath.Base(cfg.GameBookPath)
 	}
-	_ = h.tpl.ExecuteTemplate(w, "match_settings.html", map[string]any{
-		"Cfg":      cfg,
-		"Books":    books,
-		"BookName": bookName,
-		"Page":     "matches",
+	queued, err := h.store.GameQueueSize(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	engines, err := h.store.ListEngines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.render(w, "match_settings.html", map[string]any{
+		"Cfg":        cfg,
+		"Books":      books,
+		"BookName":   bookName,
+		"Boards":     h.r.Boards(),
+		"QueueCount": queued,
+		"Engines":    engines,
+		"Skipped":    h.r.SkippedGames(),
+		"Page":       "matches",
 	})
 }
 
+// maxQuickMatchGames caps one quick match so a typo can't queue a week of
+// games.
+const maxQuickMatchGames = 1000
+
+// queue games of one pair ahead of the scheduler, alternating colors. The
+// runner picks them up with its next game and falls back to normal
+// scheduling once they are played.
+func (h *Handler) handleAdminQuickMatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	whiteID, err := strconv.ParseInt(r.Form.Get("white_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid white engine", http.StatusBadRequest)
+		return
+	}
+	blackID, err := strconv.ParseInt(r.Form.Get("black_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid black engine", http.StatusBadRequest)
+		return
+	}
+	movetime, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("movetime_ms")))
+	if err != nil || movetime <= 0 {
+		http.Error(w, "invalid movetime", http.StatusBadRequest)
+		return
+	}
+	games, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("games")))
+	if err != nil || games <= 0 || games > maxQuickMatchGames {
+		http.Error(w, fmt.Sprintf("games must be between 1 and %d", maxQuickMatchGames), http.StatusBadRequest)
+		return
+	}
+	cfg, err := h.store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	engines, err := h.store.ListEngines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	names := make(map[int64]string, len(engines))
+	for _, id := range []int64{whiteID, blackID} {
+		if err := quickMatchEngine(engines, id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, e := range engines {
+		names[e.ID] = e.Name
+	}
+
+	limit := engine.SearchLimit{Mode: engine.LimitMovetime, Value: movetime}.String()
+	entries := make([]db.GameQueueEntry, 0, games)
+	for i := 0; i < games; i++ {
+		entry := db.GameQueueEntry{
+			WhiteID:     whiteID,
+			BlackID:     blackID,
+			MovetimeMS:  movetime,
+			BookPath:    cfg.GameBookPath,
+			SearchLimit: limit,
+			Priority:    db.QuickMatchPriority,
+		}
+		if i%2 == 1 {
+			entry.WhiteID, entry.BlackID = blackID, whiteID
+		}
+		entries = append(entries, entry)
+	}
+	if err := h.store.EnqueueGames(r.Context(), entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	detail := fmt.Sprintf("%d games %s vs %s at %d ms", games, names[whiteID], names[blackID], movetime)
+	if whiteID == blackID {
+		detail += " (self-play)"
+	}
+	h.audit(r, "quick match", detail)
+	http.Redirect(w, r, "/admin/matches", http.StatusSeeOther)
+}
+
+// quickMatchEngine checks that engine id exists and gets games, so a quick
+// match isn't queued only to be skipped by the runner.
+func quickMatchEngine(engines []db.Engine, id int64) error {
+	for _, e := range engines {
+		if e.ID != id {
+			continue
+		}
+		if reason := engine.IneligibleReason(e); reason != "" {
+			return fmt.Errorf("engine %s %s", e.Name, reason)
+		}
+		return nil
+	}
+	return fmt.Errorf("engine #%d does not exist", id)
+}
+
+// the queue is the only scheduling state; dropping it makes the next refill
+// rebalance from the current pairs and game counts. Like the resets after
+// engine changes, quick matches stay queued.
+func (h *Handler) handleAdminQueueReset(w http.ResponseWriter, r *http.Request) {
+	dropped, err := h.store.ClearScheduledGames(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, "reset queue", fmt.Sprintf("dropped %d scheduled games", dropped))
+	http.Redirect(w, r, "/admin/matches", http.StatusSeeOther)
+}
+
+func (h *Handler) handleAdminBoardAbort(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("board")))
+	if err != nil {
+		http.Error(w, "invalid board", http.StatusBadRequest)
+		return
+	}
+	live := h.r.Live()
+	if err := h.r.AbortBoard(index); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	h.audit(r, "abort game", fmt.Sprintf("board %d: %s vs %s", index, live.White, live.Black))
+	http.Redirect(w, r, "/admin/matches", http.StatusSeeOther)
+}
+
 func (h *Handler) handleAdminEngines(w http.ResponseWriter, r *http.Request) {
 	cfg, err := h.store.GetSettings(r.Context())
 	if err != nil {
@@ -202,7 +549,16 @@ func (h *Handler) handleAdminEngines(w http.ResponseWriter, r *http.Request) {
 	view := buildAdminView(cfg, engines, nil, gameCounts)
 	view.Page = "engines"
 	view.UnusedEngines = buildUnusedEngineViews(h.enginesDir, engines, engineBinaries)
-	_ = h.tpl.ExecuteTemplate(w, "engine_settings.html", view)
+	computeMS, err := h.store.EngineComputeTime(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range view.Engines {
+		view.Engines[i].Options = h.testedOptions(view.Engines[i].ID)
+		view.Engines[i].ComputeMS = computeMS[view.Engines[i].ID]
+	}
+	h.render(w, "engine_settings.html", view)
 }
 
 func (h *Handler) handleAdminEnginesSave(w http.ResponseWriter, r *http.Request) {
@@ -235,38 +591,54 @@ func (h *Handler) handleAdminEnginesSave(w http.ResponseWriter, r *http.Request)
 		if bins, err := listEngineBinaries(h.enginesDir); err == nil {
 			view.UnusedEngines = buildUnusedEngineViews(h.enginesDir, current, bins)
 		}
-		_ = h.tpl.ExecuteTemplate(w, "engine_settings.html", view)
+		h.render(w, "engine_settings.html", view)
 		return
 	}
 
-	if errMap := testEngines(r.Context(), parsed); len(errMap) > 0 {
+	errMap := h.checkEnginePaths(parsed, currentByID)
+	var options map[int][]string
+	if len(errMap) == 0 {
+		errMap, options = testEngines(r.Context(), parsed)
+	}
+	if len(errMap) > 0 {
 		view.Engines = buildEngineViewsFromList(parsed, errMap, gameCounts)
 		view.Page = "engines"
 		if bins, err := listEngineBinaries(h.enginesDir); err == nil {
 			view.UnusedEngines = buildUnusedEngineViews(h.enginesDir, current, bins)
 		}
-		_ = h.tpl.ExecuteTemplate(w, "engine_settings.html", view)
+		h.render(w, "engine_settings.html", view)
 		return
 	}
 	seen := make(map[int64]bool)
 	addedNew := false
-	for _, e := range parsed {
+	for i, e := range parsed {
 		if e.ID == 0 {
-			if _, err := h.store.InsertEngine(r.Context(), e); err != nil {
+			id, err := h.store.InsertEngine(r.Context(), e)
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+			e.ID = id
+			h.setTestedOptions(id, options[i])
+			h.audit(r, "add engine", engineLabel(e)+" "+e.Path)
 			addedNew = true
 			continue
 		}
+		h.setTestedOptions(e.ID, options[i])
 		seen[e.ID] = true
 		if err := h.store.UpdateEngine(r.Context(), e); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// the summaries show engine names
+		h.summaries.invalidate()
+		e.Tags = db.NormalizeTags(e.Tags)
+		if changes := fieldChanges(currentByID[e.ID], e, "engine_elo"); changes != "" {
+			h.audit(r, "edit engine", engineLabel(e)+": "+changes)
+		}
 	}
 	if addedNew {
-		_ = h.store.ClearGameQueue(r.Context())
+		_, _ = h.store.ClearScheduledGames(r.Context())
 	}
 
 	errByID := make(map[int64]string)
@@ -278,9 +650,21 @@ func (h *Handler) handleAdminEnginesSave(w http.ResponseWriter, r *http.Request)
 			errByID[e.ID] = "engine used by games"
 			continue
 		}
+		// evals.engine_id is ON DELETE RESTRICT as well
+		evalCount, err := h.store.EngineEvalCount(r.Context(), e.ID)
+		if err != nil {
+			errByID[e.ID] = err.Error()
+			continue
+		}
+		if evalCount > 0 {
+			errByID[e.ID] = fmt.Sprintf("engine used by %d evals (use Delete to remove them too)", evalCount)
+			continue
+		}
 		if err := h.store.DeleteEngine(r.Context(), e.ID); err != nil {
 			errByID[e.ID] = err.Error()
+			continue
 		}
+		h.audit(r, "delete engine", engineLabel(e))
 	}
 	if len(errByID) > 0 {
 		fresh, err := h.store.ListEngines(r.Context())
@@ -298,7 +682,7 @@ func (h *Handler) handleAdminEnginesSave(w http.ResponseWriter, r *http.Request)
 		if bins, err := listEngineBinaries(h.enginesDir); err == nil {
 			view.UnusedEngines = buildUnusedEngineViews(h.enginesDir, fresh, bins)
 		}
-		_ = h.tpl.ExecuteTemplate(w, "engine_settings.html", view)
+		h.render(w, "engine_settings.html", view)
 		return
 	}
 
@@ -316,8 +700,32 @@ func (h *Handler) handleAdminEnginePrune(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid engine id", http.StatusBadRequest)
 		return
 	}
-	_ = h.store.ClearGameQueue(r.Context())
-	if _, err := h.store.DeleteGamesByEngine(r.Context(), engineID); err != nil {
+	eng, err := h.store.EngineByID(r.Context(), engineID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	counts, _ := h.store.EngineGameCounts(r.Context())
+	binary, err := h.reclaimableBinary(r.Context(), eng)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg := fmt.Sprintf("Delete engine %q together with its %d games and its evaluations?", eng.Name, counts[engineID])
+	if binary != "" {
+		msg += fmt.Sprintf(" No other engine uses its binary %s, which is removed from the engines dir.", binary)
+	}
+	if !h.requireConfirm(w, r, "engines", "Delete engine", msg, "/admin/engines") {
+		return
+	}
+	_, _ = h.store.ClearScheduledGames(r.Context())
+	if _, err := h.store.DeleteQueuedGamesByEngine(r.Context(), engineID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = h.store.DeleteGamesByEngine(r.Context(), engineID)
+	h.summaries.invalidate()
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -329,9 +737,180 @@ func (h *Handler) handleAdminEnginePrune(w http.ResponseWriter, r *http.Request)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, "prune engine", fmt.Sprintf("%s with %d games", engineLabel(eng), counts[engineID]))
+	if binary != "" {
+		h.reclaimEngineBinary(r, eng)
+	}
 	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
 }
 
+func (h *Handler) handleAdminEngineMerge(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keepID, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("keep_id")), 10, 64)
+	if err != nil || keepID == 0 {
+		http.Error(w, "invalid engine id", http.StatusBadRequest)
+		return
+	}
+	mergeID, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("merge_id")), 10, 64)
+	if err != nil || mergeID == 0 {
+		http.Error(w, "invalid engine id", http.StatusBadRequest)
+		return
+	}
+	if keepID == mergeID {
+		http.Error(w, "cannot merge an engine into itself", http.StatusBadRequest)
+		return
+	}
+	keep, err := h.store.EngineByID(r.Context(), keepID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	merge, err := h.store.EngineByID(r.Context(), mergeID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	counts, _ := h.store.EngineGameCounts(r.Context())
+	binary, err := h.reclaimableBinary(r.Context(), merge)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg := fmt.Sprintf("Merge %q into %q? Its %d games and its evaluations move to %q and %q is deleted.",
+		merge.Name, keep.Name, counts[mergeID], keep.Name, merge.Name)
+	if binary != "" {
+		msg += fmt.Sprintf(" No other engine uses its binary %s, which is removed from the engines dir.", binary)
+	}
+	if !h.requireConfirm(w, r, "engines", "Merge engines", msg, "/admin/engines") {
+		return
+	}
+	if err := h.store.MergeEngines(r.Context(), keepID, mergeID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.summaries.invalidate()
+	h.audit(r, "merge engines", fmt.Sprintf("%s into %s", engineLabel(merge), engineLabel(keep)))
+	if binary != "" {
+		h.reclaimEngineBinary(r, merge)
+	}
+	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+}
+
+// benchTimeout bounds a single benchmark run.
+const benchTimeout = 2 * time.Minute
+
+// handleAdminEnginePause stops or resumes scheduling games for an engine.
+// Its queued games are dropped as the runner reaches them; a game in
+// progress is finished.
+func (h *Handler) handleAdminEnginePause(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engineID, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("engine_id")), 10, 64)
+	if err != nil || engineID == 0 {
+		http.Error(w, "invalid engine id", http.StatusBadRequest)
+		return
+	}
+	paused := r.Form.Get("paused") == "1"
+	e, err := h.store.EngineByID(r.Context(), engineID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	if err := h.store.SetEnginePaused(r.Context(), engineID, paused); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	action := "resume engine"
+	if paused {
+		action = "pause engine"
+	}
+	h.audit(r, action, engineLabel(e))
+	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+}
+
+// handleAdminEngineBench runs a benchmark and stores the node count and
+// speed as the engine's baseline.
+func (h *Handler) handleAdminEngineBench(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engineID, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("engine_id")), 10, 64)
+	if err != nil || engineID == 0 {
+		http.Error(w, "invalid engine id", http.StatusBadRequest)
+		return
+	}
+	e, err := h.store.EngineByID(r.Context(), engineID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	args, err := engine.SplitArgs(e.Args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), benchTimeout)
+	defer cancel()
+	res, err := engine.Bench(ctx, e.Path, args)
+	if err != nil {
+		http.Error(w, "bench failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.SetEngineBench(r.Context(), engineID, res.Nodes, res.NPS); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, "bench engine", fmt.Sprintf("%s: %d nodes, %d nps (%s)", engineLabel(e), res.Nodes, res.NPS, res.Method))
+	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+}
+
+// /admin/engines/test starts an engine and remembers the UCI options it
+// offers, so the admin can see what its init commands may set.
+func (h *Handler) handleAdminEngineTest(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engineID, err := strconv.ParseInt(strings.TrimSpace(r.Form.Get("engine_id")), 10, 64)
+	if err != nil || engineID == 0 {
+		http.Error(w, "invalid engine id", http.StatusBadRequest)
+		return
+	}
+	e, err := h.store.EngineByID(r.Context(), engineID)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	errMap, options := testEngines(r.Context(), []db.Engine{e})
+	if msg, failed := errMap[0]; failed {
+		http.Error(w, "test failed: "+msg, http.StatusInternalServerError)
+		return
+	}
+	h.setTestedOptions(engineID, options[0])
+	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+}
+
+func (h *Handler) setTestedOptions(engineID int64, options []string) {
+	if options == nil {
+		return
+	}
+	h.optionsMu.Lock()
+	defer h.optionsMu.Unlock()
+	h.engineOptions[engineID] = options
+}
+
+func (h *Handler) testedOptions(engineID int64) []string {
+	h.optionsMu.Lock()
+	defer h.optionsMu.Unlock()
+	return h.engineOptions[engineID]
+}
+
 func (h *Handler) handleAdminEngineDuplicate(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -345,10 +924,26 @@ func (h *Handler) handleAdminEngineDuplicate(w http.ResponseWriter, r *http.Requ
 	}
 	name := strings.TrimSpace(r.Form.Get("engine_name"))
 	args := strings.TrimSpace(r.Form.Get("engine_args"))
+	if _, err := engine.SplitArgs(args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	init := r.Form.Get("engine_init")
+	skipNewGame := checkboxValue(r.Form.Get("engine_skip_newgame"))
+	strengthElo, err := parseStrengthElo(r.Form.Get("engine_strength_elo"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	notes := strings.TrimSpace(r.Form.Get("engine_notes"))
+	tags := r.Form.Get("engine_tags")
 	original, err := h.store.EngineByID(r.Context(), engineID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storeError(w, err, "unknown engine")
+		return
+	}
+	if err := checkStrength(r.Context(), original.Path, args, strengthElo); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if name == "" {
@@ -359,17 +954,22 @@ func (h *Handler) handleAdminEngineDuplicate(w http.ResponseWriter, r *http.Requ
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_, err = h.store.InsertEngine(r.Context(), db.Engine{
-		Name: unique,
-		Path: original.Path,
-		Args: args,
-		Init: init,
+	id, err := h.store.InsertEngine(r.Context(), db.Engine{
+		Name:        unique,
+		Path:        original.Path,
+		Args:        args,
+		Init:        init,
+		SkipNewGame: skipNewGame,
+		StrengthElo: strengthElo,
+		Notes:       notes,
+		Tags:        tags,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.store.ClearGameQueue(r.Context())
+	h.audit(r, "add engine", fmt.Sprintf("%s (id %d) as a copy of %s", unique, id, engineLabel(original)))
+	_, _ = h.store.ClearScheduledGames(r.Context())
 	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
 }
 
@@ -391,7 +991,7 @@ func (h *Handler) handleAdminEngineRename(w http.ResponseWriter, r *http.Request
 	}
 	original, err := h.store.EngineByID(r.Context(), engineID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storeError(w, err, "unknown engine")
 		return
 	}
 	if name != original.Name {
@@ -407,17 +1007,35 @@ func (h *Handler) handleAdminEngineRename(w http.ResponseWriter, r *http.Request
 			}
 		}
 	}
-	if err := h.store.UpdateEngine(r.Context(), db.Engine{
-		ID:   original.ID,
-		Name: name,
-		Path: original.Path,
-		Args: original.Args,
-		Init: original.Init,
-	}); err != nil {
+	notes := original.Notes
+	if _, ok := r.Form["engine_notes"]; ok {
+		notes = strings.TrimSpace(r.Form.Get("engine_notes"))
+	}
+	tags := original.Tags
+	if _, ok := r.Form["engine_tags"]; ok {
+		tags = r.Form.Get("engine_tags")
+	}
+	updated := db.Engine{
+		ID:          original.ID,
+		Name:        name,
+		Path:        original.Path,
+		Args:        original.Args,
+		Init:        original.Init,
+		SkipNewGame: original.SkipNewGame,
+		StrengthElo: original.StrengthElo,
+		Notes:       notes,
+		Tags:        tags,
+	}
+	if err := h.store.UpdateEngine(r.Context(), updated); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.store.ClearGameQueue(r.Context())
+	h.summaries.invalidate()
+	updated.Tags = db.NormalizeTags(updated.Tags)
+	if changes := fieldChanges(original, updated, "engine_elo"); changes != "" {
+		h.audit(r, "edit engine", engineLabel(original)+": "+changes)
+	}
+	_, _ = h.store.ClearScheduledGames(r.Context())
 	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
 }
 
@@ -428,7 +1046,19 @@ func (h *Handler) handleAdminEngineAddUnused(w http.ResponseWriter, r *http.Requ
 	}
 	name := strings.TrimSpace(r.Form.Get("engine_name"))
 	args := strings.TrimSpace(r.Form.Get("engine_args"))
+	if _, err := engine.SplitArgs(args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	init := r.Form.Get("engine_init")
+	skipNewGame := checkboxValue(r.Form.Get("engine_skip_newgame"))
+	strengthElo, err := parseStrengthElo(r.Form.Get("engine_strength_elo"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	notes := strings.TrimSpace(r.Form.Get("engine_notes"))
+	tags := r.Form.Get("engine_tags")
 	binary := strings.TrimSpace(r.Form.Get("engine_binary"))
 	if binary == "" {
 		http.Error(w, "engine binary required", http.StatusBadRequest)
@@ -456,32 +1086,60 @@ func (h *Handler) handleAdminEngineAddUnused(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	enginePath := filepath.Join(h.enginesDir, binary)
-	for _, engine := range current {
-		if filepath.Clean(engine.Path) == enginePath {
-			http.Error(w, "engine already exists", http.StatusBadRequest)
-			return
-		}
+	inUse, err := binaryInUse(current, enginePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inUse {
+		http.Error(w, "engine already exists", http.StatusBadRequest)
+		return
+	}
+	// an identical binary another engine runs is shared rather than kept twice
+	shared, err := h.sharedBinary(current, enginePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 	path := enginePath
+	if shared != "" {
+		path = shared
+	}
 	if name == "" {
 		name = binary
 	}
+	if err := checkStrength(r.Context(), path, args, strengthElo); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	unique, err := h.uniqueEngineName(r.Context(), name)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_, err = h.store.InsertEngine(r.Context(), db.Engine{
-		Name: unique,
-		Path: path,
-		Args: args,
-		Init: init,
+	id, err := h.store.InsertEngine(r.Context(), db.Engine{
+		Name:        unique,
+		Path:        path,
+		Args:        args,
+		Init:        init,
+		SkipNewGame: skipNewGame,
+		StrengthElo: strengthElo,
+		Notes:       notes,
+		Tags:        tags,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.store.ClearGameQueue(r.Context())
+	h.audit(r, "add engine", fmt.Sprintf("%s (id %d) %s", unique, id, path))
+	if shared != "" {
+		if err := os.Remove(enginePath); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, "delete binary", fmt.Sprintf("%s (same content as %s)", binary, filepath.Base(shared)))
+	}
+	_, _ = h.store.ClearScheduledGames(r.Context())
 	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
 }
 
@@ -517,29 +1175,44 @@ func (h *Handler) handleAdminEngineDeleteUnused(w http.ResponseWriter, r *http.R
 		return
 	}
 	enginePath := filepath.Join(h.enginesDir, binary)
-	for _, engine := range current {
-		if filepath.Clean(engine.Path) == enginePath {
-			http.Error(w, "engine already exists", http.StatusBadRequest)
-			return
-		}
+	inUse, err := binaryInUse(current, enginePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inUse {
+		http.Error(w, "engine already exists", http.StatusBadRequest)
+		return
 	}
 	path := enginePath
 	if err := os.Remove(path); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, "delete binary", binary)
 	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
 }
 
 type EngineView struct {
-	ID    int64
-	Index int
-	Name  string
-	Path  string
-	Args  string
-	Init  string
-	Error string
-	Games int
+	ID          int64
+	Index       int
+	Name        string
+	Path        string
+	Args        string
+	Init        string
+	SkipNewGame bool
+	StrengthElo int
+	Paused      bool
+	// option lines from the last successful test, if any
+	Options    []string
+	Notes      string
+	Tags       string
+	Error      string
+	Games      int
+	ComputeMS  int64
+	BenchNodes int64
+	BenchNPS   int64
+	BenchAt    string
 }
 
 type UnusedEngineView struct {
@@ -559,13 +1232,21 @@ func buildAdminView(cfg db.Settings, engines []db.Engine, errByID map[int64]stri
 	views := make([]EngineView, 0, len(engines))
 	for i, e := range engines {
 		view := EngineView{
-			ID:    e.ID,
-			Index: i,
-			Name:  e.Name,
-			Path:  e.Path,
-			Args:  e.Args,
-			Init:  e.Init,
-			Games: gameCounts[e.ID],
+			ID:          e.ID,
+			Index:       i,
+			Name:        e.Name,
+			Path:        e.Path,
+			Args:        e.Args,
+			Init:        e.Init,
+			SkipNewGame: e.SkipNewGame,
+			StrengthElo: e.StrengthElo,
+			Paused:      e.Paused,
+			Notes:       e.Notes,
+			Tags:        e.Tags,
+			Games:       gameCounts[e.ID],
+			BenchNodes:  e.BenchNodes,
+			BenchNPS:    e.BenchNPS,
+			BenchAt:     e.BenchAt,
 		}
 		if errByID != nil {
 			view.Error = errByID[e.ID]
@@ -619,6 +1300,23 @@ func parseEnginesFromForm(r *http.Request, existing map[int64]db.Engine) ([]db.E
 		path := strings.TrimSpace(r.Form.Get(fmt.Sprintf("engine_path_%d", i)))
 		args := strings.TrimSpace(r.Form.Get(fmt.Sprintf("engine_args_%d", i)))
 		init := r.Form.Get(fmt.Sprintf("engine_init_%d", i))
+		skipNewGame := checkboxValue(r.Form.Get(fmt.Sprintf("engine_skip_newgame_%d", i)))
+		strengthElo := existing[id].StrengthElo
+		if vals, ok := r.Form[fmt.Sprintf("engine_strength_elo_%d", i)]; ok && len(vals) > 0 {
+			v, err := parseStrengthElo(vals[0])
+			if err != nil {
+				errMap[len(engines)] = err.Error()
+			}
+			strengthElo = v
+		}
+		notes := existing[id].Notes
+		if vals, ok := r.Form[fmt.Sprintf("engine_notes_%d", i)]; ok && len(vals) > 0 {
+			notes = strings.TrimSpace(vals[0])
+		}
+		tags := existing[id].Tags
+		if vals, ok := r.Form[fmt.Sprintf("engine_tags_%d", i)]; ok && len(vals) > 0 {
+			tags = db.NormalizeTags(vals[0])
+		}
 		if name == "" && path == "" && args == "" && strings.TrimSpace(init) == "" {
 			continue
 		}
@@ -633,6 +1331,11 @@ func parseEnginesFromForm(r *http.Request, existing map[int64]db.Engine) ([]db.E
 				errMap[len(engines)] = "path required"
 			}
 		}
+		if _, err := engine.SplitArgs(args); err != nil {
+			if _, ok := errMap[len(engines)]; !ok {
+				errMap[len(engines)] = err.Error()
+			}
+		}
 		if prev, ok := nameIndex[name]; ok && name != "" {
 			errMap[prev] = "duplicate name"
 			errMap[len(engines)] = "duplicate name"
@@ -641,19 +1344,27 @@ func parseEnginesFromForm(r *http.Request, existing map[int64]db.Engine) ([]db.E
 		}
 
 		engines = append(engines, db.Engine{
-			ID:   id,
-			Name: name,
-			Path: path,
-			Args: args,
-			Init: init,
+			ID:          id,
+			Name:        name,
+			Path:        path,
+			Args:        args,
+			Init:        init,
+			SkipNewGame: skipNewGame,
+			StrengthElo: strengthElo,
+			Notes:       notes,
+			Tags:        tags,
 		})
 		viewEngines = append(viewEngines, EngineView{
-			ID:    id,
-			Index: len(engines) - 1,
-			Name:  name,
-			Path:  path,
-			Args:  args,
-			Init:  init,
+			ID:          id,
+			Index:       len(engines) - 1,
+			Name:        name,
+			Path:        path,
+			Args:        args,
+			Init:        init,
+			SkipNewGame: skipNewGame,
+			StrengthElo: strengthElo,
+			Notes:       notes,
+			Tags:        tags,
 		})
 	}
 
@@ -669,6 +1380,44 @@ func parseEnginesFromForm(r *http.Request, existing map[int64]db.Engine) ([]db.E
 	return engines, AdminView{Engines: viewEngines}, true
 }
 
+// parseStrengthElo reads an engine's UCI_Elo cap; empty or 0 is full strength.
+func parseStrengthElo(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid strength Elo %q", raw)
+	}
+	return v, nil
+}
+
+// checkStrength starts the engine to confirm it can be capped at elo.
+func checkStrength(ctx context.Context, path, args string, elo int) error {
+	if elo <= 0 {
+		return nil
+	}
+	splitArgs, err := engine.SplitArgs(args)
+	if err != nil {
+		return err
+	}
+	eng := engine.NewUCIEngine(path, splitArgs)
+	testCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := eng.Start(testCtx); err != nil {
+		return err
+	}
+	defer func() { _ = eng.Close() }()
+	return engine.CheckStrengthOptions(eng.Options(), elo)
+}
+
+// checkboxValue interprets an HTML checkbox (or similar boolean) form value.
+func checkboxValue(raw string) bool {
+	raw = strings.TrimSpace(raw)
+	return raw == "1" || strings.EqualFold(raw, "true") || strings.EqualFold(raw, "on")
+}
+
 func listEngineBinaries(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -731,13 +1480,21 @@ func buildEngineViewsFromList(engines []db.Engine, errByIndex map[int]string, ga
 	views := make([]EngineView, 0, len(engines))
 	for i, e := range engines {
 		view := EngineView{
-			ID:    e.ID,
-			Index: i,
-			Name:  e.Name,
-			Path:  e.Path,
-			Args:  e.Args,
-			Init:  e.Init,
-			Games: gameCounts[e.ID],
+			ID:          e.ID,
+			Index:       i,
+			Name:        e.Name,
+			Path:        e.Path,
+			Args:        e.Args,
+			Init:        e.Init,
+			SkipNewGame: e.SkipNewGame,
+			StrengthElo: e.StrengthElo,
+			Paused:      e.Paused,
+			Notes:       e.Notes,
+			Tags:        e.Tags,
+			Games:       gameCounts[e.ID],
+			BenchNodes:  e.BenchNodes,
+			BenchNPS:    e.BenchNPS,
+			BenchAt:     e.BenchAt,
 		}
 		if errByIndex != nil {
 			view.Error = errByIndex[i]
@@ -767,24 +1524,87 @@ func listBookOptions(dir string) ([]string, error) {
 	return options, nil
 }
 
-func testEngines(ctx context.Context, engines []db.Engine) map[int]string {
+// checkEnginePaths enforces TETHYS_ENGINE_ALLOWED_DIRS on new or changed
+// engine paths; engines whose path is unchanged keep working.
+func (h *Handler) checkEnginePaths(engines []db.Engine, existing map[int64]db.Engine) map[int]string {
 	errMap := make(map[int]string)
+	if len(h.engineDirs) == 0 {
+		return errMap
+	}
+	dirs := append([]string{h.enginesDir}, h.engineDirs...)
+	for i, e := range engines {
+		if old, ok := existing[e.ID]; ok && e.ID != 0 && old.Path == e.Path {
+			continue
+		}
+		if e.Path == "" || engine.IsBuiltin(e.Path) || engineUnderDirs(e.Path, dirs) {
+			continue
+		}
+		errMap[i] = "engine path must be under " + strings.Join(h.engineDirs, ", ") + " or the engines upload dir"
+	}
+	return errMap
+}
+
+// engineUnderDirs resolves path (expanding ~ and $VAR, following symlinks)
+// and reports whether it lies inside one of dirs.
+func engineUnderDirs(path string, dirs []string) bool {
+	resolved, err := filepath.Abs(engine.ExpandPath(path))
+	if err != nil {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = real
+	}
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		base, err := filepath.Abs(engine.ExpandPath(dir))
+		if err != nil {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(base); err == nil {
+			base = real
+		}
+		rel, err := filepath.Rel(base, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// testEngines starts each engine and returns the errors and, for engines that
+// started, their UCI option lines, both by index.
+func testEngines(ctx context.Context, engines []db.Engine) (map[int]string, map[int][]string) {
+	errMap := make(map[int]string)
+	options := make(map[int][]string)
 	for i, e := range engines {
 		if e.Path == "" {
 			continue
 		}
-		eng := engine.NewUCIEngine(e.Path, strings.Fields(e.Args))
+		args, err := engine.SplitArgs(e.Args)
+		if err != nil {
+			errMap[i] = err.Error()
+			continue
+		}
+		eng := engine.NewUCIEngine(e.Path, args)
 		testCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 		if err := eng.Start(testCtx); err != nil {
 			errMap[i] = err.Error()
 			cancel()
 			continue
 		}
+		options[i] = append([]string{}, eng.Options()...)
 		if err := eng.IsReady(testCtx); err != nil {
 			errMap[i] = err.Error()
+		} else if e.StrengthElo > 0 {
+			if err := engine.CheckStrengthOptions(eng.Options(), e.StrengthElo); err != nil {
+				errMap[i] = err.Error()
+			}
 		}
 		_ = eng.Close()
 		cancel()
 	}
-	return errMap
+	return errMap, options
 }
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"tethys/internal/db"
+)
+
+const auditPageSize = 200
+
+// audit records a successful admin mutation. Failures to write the log are
+// ignored; they must not undo or block the action itself.
+func (h *Handler) audit(r *http.Request, action, detail string) {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	_ = h.store.InsertAudit(r.Context(), remote, action, detail)
+}
+
+// fieldChanges lists the db-tagged fields that differ between two values of
+// the same struct type, as "key: old -> new".
+func fieldChanges(before, after any, skip ...string) string {
+	bv, av := reflect.ValueOf(before), reflect.ValueOf(after)
+	t := bv.Type()
+	changes := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("db")
+		if key == "" || contains(skip, key) {
+			continue
+		}
+		b, a := bv.Field(i).Interface(), av.Field(i).Interface()
+		if b != a {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, b, a))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func engineLabel(e db.Engine) string {
+	return fmt.Sprintf("%s (id %d)", e.Name, e.ID)
+}
+
+func (h *Handler) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.store.ListAudit(r.Context(), auditPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.render(w, "audit.html", map[string]any{
+		"Entries": entries,
+		"Page":    "audit",
+	})
+}
@@ -8,10 +8,10 @@ import (
 )
 
 type SquareView struct {
-	Glyph  string
-	Class  string
-	Square string
-	Piece  string
+	Glyph  string `json:"glyph"`
+	Class  string `json:"class"`
+	Square string `json:"square"`
+	Piece  string `json:"piece"`
 }
 
 func boardFromPosition(pos *chess.Position) [][]SquareView {
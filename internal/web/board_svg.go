@@ -0,0 +1,55 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const svgSquare = 44
+
+// render a position as a standalone SVG, using the same glyphs and square
+// colors as the HTML board.
+func boardSVG(pos *chess.Position) []byte {
+	size := svgSquare * 8
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+	buf.WriteString("\n")
+	for y, row := range boardFromPosition(pos) {
+		for x, sq := range row {
+			fill := "#1b2534"
+			if strings.Contains(sq.Class, "light") {
+				fill = "#2a3b52"
+			}
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x*svgSquare, y*svgSquare, svgSquare, svgSquare, fill)
+			if sq.Glyph != "" {
+				fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="32" text-anchor="middle" dominant-baseline="central" fill="#e6edf3">%s</text>`,
+					x*svgSquare+svgSquare/2, y*svgSquare+svgSquare/2, html.EscapeString(sq.Glyph))
+			}
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// /live/board.svg renders the live position, or ?fen= if given.
+func (h *Handler) handleBoardSVG(w http.ResponseWriter, r *http.Request) {
+	fen := strings.TrimSpace(r.URL.Query().Get("fen"))
+	if fen == "" {
+		fen = h.r.Live().FEN
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		http.Error(w, "invalid fen", http.StatusBadRequest)
+		return
+	}
+	pos := chess.NewGame(opt).Position()
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	_, _ = w.Write(boardSVG(pos))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/notnil/chess"
 
 	"tethys/internal/book"
+	"tethys/internal/engine"
 )
 
 type BookMoveView struct {
@@ -40,14 +41,14 @@ func (h *Handler) handleBookExplorer(w http.ResponseWriter, r *http.Request) {
 
 	if bookPath == "" {
 		view["Error"] = "No opening book configured."
-		_ = h.tpl.ExecuteTemplate(w, "book_explorer.html", view)
+		h.render(w, "book_explorer.html", view)
 		return
 	}
 
-	bk, err := book.Load(bookPath)
+	bk, err := book.Load(engine.ExpandPath(bookPath))
 	if err != nil {
 		view["Error"] = err.Error()
-		_ = h.tpl.ExecuteTemplate(w, "book_explorer.html", view)
+		h.render(w, "book_explorer.html", view)
 		return
 	}
 
@@ -57,7 +58,7 @@ func (h *Handler) handleBookExplorer(w http.ResponseWriter, r *http.Request) {
 		opt, err := chess.FEN(fen)
 		if err != nil {
 			view["Error"] = "Invalid FEN."
-			_ = h.tpl.ExecuteTemplate(w, "book_explorer.html", view)
+			h.render(w, "book_explorer.html", view)
 			return
 		}
 		game := chess.NewGame(opt)
@@ -100,7 +101,7 @@ func (h *Handler) handleBookExplorer(w http.ResponseWriter, r *http.Request) {
 	view["Moves"] = moveViews
 	view["Board"] = boardFromPosition(pos)
 	view["Arrows"] = arrowsFromMoves(moves, total)
-	_ = h.tpl.ExecuteTemplate(w, "book_explorer.html", view)
+	h.render(w, "book_explorer.html", view)
 }
 
 func arrowsFromMoves(moves []book.MoveWeight, total int) []ArrowView {
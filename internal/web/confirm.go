@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+)
+
+type confirmField struct {
+	Name  string
+	Value string
+}
+
+// requireConfirm lets a destructive POST through only with confirm=1. The
+// first POST gets a page that repeats the form with confirm=1 added.
+// r.Form must already be parsed.
+func (h *Handler) requireConfirm(w http.ResponseWriter, r *http.Request, page, title, message, cancel string) bool {
+	if r.Form.Get("confirm") == "1" {
+		return true
+	}
+	names := make([]string, 0, len(r.PostForm))
+	for name := range r.PostForm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]confirmField, 0, len(names))
+	for _, name := range names {
+		for _, value := range r.PostForm[name] {
+			fields = append(fields, confirmField{Name: name, Value: value})
+		}
+	}
+	h.render(w, "confirm.html", map[string]any{
+		"Title":   title,
+		"Message": message,
+		"Action":  r.URL.Path,
+		"Fields":  fields,
+		"Cancel":  cancel,
+		"Page":    page,
+	})
+	return false
+}
@@ -0,0 +1,271 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"tethys/internal/db"
+	"tethys/internal/engine"
+)
+
+// Binaries in the engines dir are deduplicated by content: an engine added
+// from a binary identical to one another engine already runs points at that
+// file instead, and the copy is removed. Several engines may share a file.
+// Deleting the last engine that uses one removes it too, but only through an
+// action whose confirm page names the file, and only files named there are
+// removed; saving the engines form never removes anything.
+
+// resolveBinary returns the absolute path the runner starts for an engine
+// path, with ~ and $VAR expanded as engine.ExpandPath does.
+func resolveBinary(path string) string {
+	path = engine.ExpandPath(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// inEnginesDir reports whether path names a file directly in the engines dir,
+// the only place binaries are ever removed from.
+func (h *Handler) inEnginesDir(path string) bool {
+	if strings.TrimSpace(path) == "" {
+		return false
+	}
+	return filepath.Dir(resolveBinary(path)) == resolveBinary(h.enginesDir)
+}
+
+// binaryInUse reports whether one of engines runs the file at path, however
+// either path is spelled: paths are compared once resolved and then as
+// files, so a symlink or hard link counts as the same binary. An engine path
+// that can't be checked counts as a use.
+func binaryInUse(engines []db.Engine, path string) (bool, error) {
+	path = resolveBinary(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range engines {
+		if strings.TrimSpace(e.Path) == "" {
+			continue
+		}
+		other := resolveBinary(e.Path)
+		if other == path {
+			return true, nil
+		}
+		otherInfo, err := os.Stat(other)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil || os.SameFile(info, otherInfo) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func fileSHA256(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
+// sharedBinary returns the path of a file in the engines dir that one of
+// engines runs and that has the same content as path, or "" if there is none.
+// Files that are path itself under another name don't count.
+func (h *Handler) sharedBinary(engines []db.Engine, path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	want, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	checked := make(map[string]bool)
+	for _, e := range engines {
+		if !h.inEnginesDir(e.Path) {
+			continue
+		}
+		other := resolveBinary(e.Path)
+		if checked[other] {
+			continue
+		}
+		checked[other] = true
+		otherInfo, err := os.Stat(other)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if os.SameFile(info, otherInfo) {
+			continue
+		}
+		sum, err := fileSHA256(other)
+		if err != nil {
+			return "", err
+		}
+		if sum == want {
+			return other, nil
+		}
+	}
+	return "", nil
+}
+
+// reclaimBinary removes path from the engines dir once no engine uses it
+// any more and reports whether it did.
+func (h *Handler) reclaimBinary(ctx context.Context, path string) (bool, error) {
+	if !h.inEnginesDir(path) {
+		return false, nil
+	}
+	path = resolveBinary(path)
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		return false, err
+	}
+	inUse, err := binaryInUse(engines, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil || inUse {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// reclaimableBinary returns the name of the file in the engines dir that
+// deleting e would leave unused, or "" if there is none. Confirm pages list
+// it before reclaimEngineBinary removes it.
+func (h *Handler) reclaimableBinary(ctx context.Context, e db.Engine) (string, error) {
+	if !h.inEnginesDir(e.Path) {
+		return "", nil
+	}
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		return "", err
+	}
+	others := make([]db.Engine, 0, len(engines))
+	for _, other := range engines {
+		if other.ID != e.ID {
+			others = append(others, other)
+		}
+	}
+	inUse, err := binaryInUse(others, e.Path)
+	if errors.Is(err, fs.ErrNotExist) || inUse {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(resolveBinary(e.Path)), nil
+}
+
+// reclaimEngineBinary removes the binary a deleted engine ran, if no other
+// engine uses it. Failing to remove it doesn't fail the request.
+func (h *Handler) reclaimEngineBinary(r *http.Request, old db.Engine) {
+	removed, err := h.reclaimBinary(r.Context(), old.Path)
+	if err != nil {
+		log.Printf("reclaim %s: %v", old.Path, err)
+		return
+	}
+	if removed {
+		h.audit(r, "delete binary", filepath.Base(old.Path))
+	}
+}
+
+// handleAdminEngineDeleteAllUnused removes the binaries the engines page
+// listed as unused, sent as name fields. Only names that no engine runs at
+// confirm time are deleted; the rest are skipped and reported. Nothing
+// outside the engines dir is touched.
+func (h *Handler) handleAdminEngineDeleteAllUnused(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engines, err := h.store.ListEngines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	binaries, err := listEngineBinaries(h.enginesDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unused := make(map[string]bool)
+	for _, u := range buildUnusedEngineViews(h.enginesDir, engines, binaries) {
+		unused[u.Binary] = true
+	}
+	var names, skipped []string
+	for _, name := range r.PostForm["name"] {
+		if unused[name] && !slices.Contains(names, name) {
+			names = append(names, name)
+		} else if !slices.Contains(skipped, name) {
+			skipped = append(skipped, name)
+		}
+	}
+	if len(names) == 0 {
+		if len(skipped) > 0 {
+			h.audit(r, "delete binaries", "skipped "+strings.Join(skipped, ", ")+": in use or gone")
+		}
+		http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+		return
+	}
+	msg := fmt.Sprintf("Delete %d binaries no engine uses from the engines dir: %s?", len(names), strings.Join(names, ", "))
+	if len(skipped) > 0 {
+		msg += fmt.Sprintf(" %s will be kept: an engine uses them now or they are gone.", strings.Join(skipped, ", "))
+	}
+	if !h.requireConfirm(w, r, "engines", "Delete unused binaries", msg, "/admin/engines") {
+		return
+	}
+	var deleted []string
+	for _, name := range names {
+		// checks again that no engine uses it
+		removed, err := h.reclaimBinary(r.Context(), filepath.Join(h.enginesDir, name))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if removed {
+			deleted = append(deleted, name)
+		} else {
+			skipped = append(skipped, name)
+		}
+	}
+	detail := strings.Join(deleted, ", ")
+	if len(skipped) > 0 {
+		if detail != "" {
+			detail += "; "
+		}
+		detail += "skipped " + strings.Join(skipped, ", ") + ": in use or gone"
+	}
+	if detail != "" {
+		h.audit(r, "delete binaries", detail)
+	}
+	http.Redirect(w, r, "/admin/engines", http.StatusSeeOther)
+}
@@ -1,37 +1,44 @@
 package web
 
 import (
+	"compress/gzip"
 	"context"
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/notnil/chess"
 
 	"tethys/internal/db"
+	"tethys/internal/engine"
 )
 
 type MatchupRow struct {
-	AID        int64
-	BID        int64
-	A          string
-	B          string
-	MovetimeMS int
-	Wins       int
-	Losses     int
-	Draws      int
-	PointsA    float64
-	PointsB    float64
-	Total      int
-	WinPct     float64
-	LossPct    float64
-	DrawPct    float64
-	ShowNames  bool
-	RowSpan    int
+	AID         int64
+	BID         int64
+	A           string
+	B           string
+	SearchLimit string
+	Wins        int
+	Losses      int
+	Draws       int
+	PointsA     float64
+	PointsB     float64
+	Total       int
+	WinPct      float64
+	LossPct     float64
+	DrawPct     float64
+	ShowNames   bool
+	RowSpan     int
 }
 
 type ResultRow struct {
@@ -46,30 +53,48 @@ type SearchView struct {
 	WhiteID      int64
 	BlackID      int64
 	AllowSwap    bool
-	Movetime     string
+	SearchLimit  string
 	Result       string
 	Termination  string
+	Opening      string
+	ECO          string
+	MinPlies     string
+	MaxPlies     string
+	Since        string
+	Until        string
+	Order        string
+	Status       string
+	Error        string
 	Limit        int
 	Total        int
 	Rows         []db.GameDetail
 	Engines      []db.Engine
+	SearchLimits []string
 	Results      []string
 	Terminations []string
 }
 
 func (h *Handler) handleGames(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	matchups, err := h.store.ListMatchupSummaries(ctx)
+	// colors=split shows White and Black separately within each pair
+	byColor := r.URL.Query().Get("colors") == "split"
+	toggle := r.URL.Query()
+	if byColor {
+		toggle.Del("colors")
+	} else {
+		toggle.Set("colors", "split")
+	}
+	matchups, resultSummaries, summariesAt, err := h.gameSummaries(ctx, byColor)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	resultSummaries, err := h.store.ListResultSummaries(ctx)
+	searchView, err := buildSearchView(ctx, h.store, r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	searchView, err := buildSearchView(ctx, h.store, r)
+	highlights, err := h.store.GameHighlights(ctx)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -81,26 +106,26 @@ func (h *Handler) handleGames(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		rows = append(rows, MatchupRow{
-			AID:        m.AID,
-			BID:        m.BID,
-			A:          m.A,
-			B:          m.B,
-			MovetimeMS: m.MovetimeMS,
-			Wins:       m.WinsA,
-			Losses:     m.WinsB,
-			Draws:      m.Draws,
-			PointsA:    float64(m.WinsA) + 0.5*float64(m.Draws),
-			PointsB:    float64(m.WinsB) + 0.5*float64(m.Draws),
-			Total:      total,
-			WinPct:     float64(m.WinsA) * 100 / float64(total),
-			LossPct:    float64(m.WinsB) * 100 / float64(total),
-			DrawPct:    float64(m.Draws) * 100 / float64(total),
+			AID:         m.AID,
+			BID:         m.BID,
+			A:           m.A,
+			B:           m.B,
+			SearchLimit: m.SearchLimit,
+			Wins:        m.WinsA,
+			Losses:      m.WinsB,
+			Draws:       m.Draws,
+			PointsA:     float64(m.WinsA) + 0.5*float64(m.Draws),
+			PointsB:     float64(m.WinsB) + 0.5*float64(m.Draws),
+			Total:       total,
+			WinPct:      float64(m.WinsA) * 100 / float64(total),
+			LossPct:     float64(m.WinsB) * 100 / float64(total),
+			DrawPct:     float64(m.Draws) * 100 / float64(total),
 		})
 	}
 	sort.Slice(rows, func(i, j int) bool {
 		if rows[i].A == rows[j].A {
 			if rows[i].B == rows[j].B {
-				return rows[i].MovetimeMS < rows[j].MovetimeMS
+				return searchLimitLess(rows[i].SearchLimit, rows[j].SearchLimit)
 			}
 			return rows[i].B < rows[j].B
 		}
@@ -121,11 +146,16 @@ func (h *Handler) handleGames(w http.ResponseWriter, r *http.Request) {
 			seen[key] = true
 		}
 	}
-	_ = h.tpl.ExecuteTemplate(w, "game_database.html", map[string]any{
-		"Rows":       rows,
-		"ResultRows": buildResultRows(resultSummaries),
-		"Search":     searchView,
-		"Page":       "games",
+	h.render(w, "game_database.html", map[string]any{
+		"Rows":        rows,
+		"ByColor":     byColor,
+		"ColorToggle": "/games?" + toggle.Encode(),
+		"ResultRows":  buildResultRows(resultSummaries),
+		"Search":      searchView,
+		"Highlights":  highlights,
+		"SummariesAt": summariesAt.UTC().Format(time.RFC3339Nano),
+		"Query":       r.URL.RawQuery,
+		"Page":        "games",
 	})
 }
 
@@ -134,38 +164,94 @@ func buildSearchView(ctx context.Context, store *db.Store, r *http.Request) (Sea
 	engineID, _ := strconv.ParseInt(strings.TrimSpace(q.Get("engine")), 10, 64)
 	result := strings.TrimSpace(q.Get("result"))
 	termination := strings.TrimSpace(q.Get("termination"))
-	movetimeStr := strings.TrimSpace(q.Get("movetime"))
+	searchLimit := strings.TrimSpace(q.Get("search_limit"))
 	limit := 20
 	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
 		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
 			limit = v
 		}
 	}
-	movetime := 0
-	if movetimeStr != "" {
-		if v, err := strconv.Atoi(movetimeStr); err == nil {
-			movetime = v
+	if searchLimit == "" {
+		// older links filtered on movetime only
+		if v, err := strconv.Atoi(strings.TrimSpace(q.Get("movetime"))); err == nil && v > 0 {
+			searchLimit = engine.SearchLimit{Mode: engine.LimitMovetime, Value: v}.String()
 		}
 	}
 
+	minPliesStr := strings.TrimSpace(q.Get("min_plies"))
+	maxPliesStr := strings.TrimSpace(q.Get("max_plies"))
+	minPlies, _ := strconv.Atoi(minPliesStr)
+	maxPlies, _ := strconv.Atoi(maxPliesStr)
+	opening := strings.TrimSpace(q.Get("opening"))
+	ecoCode := strings.ToUpper(strings.TrimSpace(q.Get("eco")))
+	openingPrefix, filterErr := parseOpeningPrefix(opening)
+	sinceStr := strings.TrimSpace(q.Get("since"))
+	untilStr := strings.TrimSpace(q.Get("until"))
+	since, err := parseSearchDate(sinceStr, false)
+	if err != nil && filterErr == nil {
+		filterErr = err
+	}
+	until, err := parseSearchDate(untilStr, true)
+	if err != nil && filterErr == nil {
+		filterErr = err
+	}
+
 	whiteID, _ := strconv.ParseInt(strings.TrimSpace(q.Get("white")), 10, 64)
 	blackID, _ := strconv.ParseInt(strings.TrimSpace(q.Get("black")), 10, 64)
 	allowSwap := q.Get("swap") == "on"
+	order := strings.TrimSpace(q.Get("order"))
+	// only finished games are listed unless another status is asked for,
+	// or the "*" result itself. unfinished=show is the old checkbox.
+	status := strings.TrimSpace(q.Get("status"))
+	if status == "" && q.Get("unfinished") == "show" {
+		status = "all"
+	}
+	includeUnfinished := false
+	filterStatus := ""
+	switch status {
+	case "":
+	case "all":
+		includeUnfinished = true
+	case db.GameStatusFinished, db.GameStatusAborted, db.GameStatusUnfinished:
+		filterStatus = status
+	default:
+		if filterErr == nil {
+			filterErr = fmt.Errorf("invalid status %q", status)
+		}
+	}
 
 	filter := db.GameSearchFilter{
-		EngineID:    engineID,
-		WhiteID:     whiteID,
-		BlackID:     blackID,
-		AllowSwap:   allowSwap,
-		MovetimeMS:  movetime,
-		Result:      result,
-		Termination: termination,
-	}
-	total, rows, err := store.SearchGames(ctx, filter, limit)
+		EngineID:      engineID,
+		WhiteID:       whiteID,
+		BlackID:       blackID,
+		AllowSwap:     allowSwap,
+		SearchLimit:   searchLimit,
+		Result:        result,
+		Termination:   termination,
+		OpeningPrefix: openingPrefix,
+		ECO:           ecoCode,
+		MinPlies:      minPlies,
+		MaxPlies:      maxPlies,
+		Since:         since,
+		Until:         until,
+		Order:         order,
+
+		IncludeUnfinished: includeUnfinished,
+		Status:            filterStatus,
+	}
+	var total int
+	var rows []db.GameDetail
+	if filterErr == nil {
+		total, rows, err = store.SearchGames(ctx, filter, limit)
+		if err != nil {
+			return SearchView{}, err
+		}
+	}
+	engines, err := store.ListEngines(ctx)
 	if err != nil {
 		return SearchView{}, err
 	}
-	engines, err := store.ListEngines(ctx)
+	searchLimits, err := store.ListSearchLimits(ctx)
 	if err != nil {
 		return SearchView{}, err
 	}
@@ -182,13 +268,23 @@ func buildSearchView(ctx context.Context, store *db.Store, r *http.Request) (Sea
 		WhiteID:      whiteID,
 		BlackID:      blackID,
 		AllowSwap:    allowSwap,
-		Movetime:     movetimeStr,
+		SearchLimit:  searchLimit,
 		Result:       result,
 		Termination:  termination,
+		Opening:      opening,
+		ECO:          ecoCode,
+		MinPlies:     minPliesStr,
+		MaxPlies:     maxPliesStr,
+		Since:        sinceStr,
+		Until:        untilStr,
+		Order:        order,
+		Status:       status,
+		Error:        errorText(filterErr),
 		Limit:        limit,
 		Total:        total,
 		Rows:         rows,
 		Engines:      engines,
+		SearchLimits: searchLimits,
 		Results:      results,
 		Terminations: terminations,
 	}, nil
@@ -205,10 +301,17 @@ func (h *Handler) handleResultDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing result", http.StatusBadRequest)
 		return
 	}
-	if _, err := h.store.DeleteResultGames(r.Context(), result, termination); err != nil {
+	msg := fmt.Sprintf("Delete all games with result %s?", resultLabel(result, termination))
+	if !h.requireConfirm(w, r, "games", "Delete games", msg, "/games") {
+		return
+	}
+	n, err := h.store.DeleteResultGames(r.Context(), result, termination)
+	h.summaries.invalidate()
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, "delete games", fmt.Sprintf("%d games with result %s", n, resultLabel(result, termination)))
 	http.Redirect(w, r, "/games", http.StatusSeeOther)
 }
 
@@ -219,16 +322,138 @@ func (h *Handler) handleResultDownload(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing result", http.StatusBadRequest)
 		return
 	}
-	lines, err := h.store.ResultMovesLines(r.Context(), result, termination)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	label := sanitizeFilename(resultLabel(result, termination))
+	filename := fmt.Sprintf("result-%s.txt", label)
+	streamMovesLines(w, r, filename, func(out io.Writer) error {
+		return h.store.WriteResultMovesLines(r.Context(), out, result, termination)
+	})
+}
+
+// /games/result.pgn is the same bucket as PGN, with player and opening tags.
+func (h *Handler) handleResultPGN(w http.ResponseWriter, r *http.Request) {
+	result := strings.TrimSpace(r.URL.Query().Get("result"))
+	termination := strings.TrimSpace(r.URL.Query().Get("termination"))
+	if result == "" {
+		http.Error(w, "missing result", http.StatusBadRequest)
 		return
 	}
 	label := sanitizeFilename(resultLabel(result, termination))
-	filename := fmt.Sprintf("result-%s.txt", label)
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	filename := fmt.Sprintf("result-%s.pgn", label)
+	streamAttachment(w, r, filename, "application/x-chess-pgn", func(out io.Writer) error {
+		return h.store.WriteResultPGN(r.Context(), out, result, termination)
+	})
+}
+
+// /download/all.txt exports every stored game, one line per game.
+func (h *Handler) handleDownloadAll(w http.ResponseWriter, r *http.Request) {
+	streamMovesLines(w, r, "all-games.txt", func(out io.Writer) error {
+		return h.store.WriteAllMovesLines(r.Context(), out)
+	})
+}
+
+// /engine/{id}/games.txt and .pgn export every game the engine played, as
+// either color.
+func (h *Handler) handleEngineGamesTxt(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.downloadEngine(w, r)
+	if !ok {
+		return
+	}
+	filename := fmt.Sprintf("%s-games.txt", sanitizeFilename(e.Name))
+	streamMovesLines(w, r, filename, func(out io.Writer) error {
+		return h.store.WriteEngineMovesLines(r.Context(), out, e.ID)
+	})
+}
+
+func (h *Handler) handleEngineGamesPGN(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.downloadEngine(w, r)
+	if !ok {
+		return
+	}
+	filename := fmt.Sprintf("%s-games.pgn", sanitizeFilename(e.Name))
+	streamAttachment(w, r, filename, "application/x-chess-pgn", func(out io.Writer) error {
+		return h.store.WriteEnginePGN(r.Context(), out, e.ID)
+	})
+}
+
+func (h *Handler) downloadEngine(w http.ResponseWriter, r *http.Request) (db.Engine, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return db.Engine{}, false
+	}
+	e, err := h.store.EngineByID(r.Context(), id)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return db.Engine{}, false
+	}
+	return e, true
+}
+
+// streamMovesLines sends write's output as a text attachment.
+func streamMovesLines(w http.ResponseWriter, r *http.Request, filename string, write func(io.Writer) error) {
+	streamAttachment(w, r, filename, "text/plain; charset=utf-8", write)
+}
+
+// streamAttachment sends write's output as an attachment, gzipped if the
+// client accepts it. Errors before the first byte become a 500; later ones
+// can only cut the download short.
+func streamAttachment(w http.ResponseWriter, r *http.Request, filename, contentType string, write func(io.Writer) error) {
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	_, _ = w.Write([]byte(lines))
+	w.Header().Add("Vary", "Accept-Encoding")
+	var dst io.Writer = w
+	var gz *gzip.Writer
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		dst = gz
+	}
+	out := &trackingWriter{w: dst}
+	if err := write(out); err != nil && !out.wrote {
+		w.Header().Del("Content-Disposition")
+		w.Header().Del("Content-Encoding")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if gz != nil {
+		_ = gz.Close()
+	}
+}
+
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses it
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
+		}
+		return true
+	}
+	return false
+}
+
+type trackingWriter struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.wrote = true
+	return t.w.Write(p)
 }
 
 func buildResultRows(rows []db.ResultSummary) []ResultRow {
@@ -291,11 +516,7 @@ func (h *Handler) handleGameView(w http.ResponseWriter, r *http.Request) {
 	}
 	game, err := h.store.GetGame(r.Context(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.NotFound(w, r)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storeError(w, err, "unknown game")
 		return
 	}
 	logs, err := h.store.ListEngineLogsByGame(r.Context(), id)
@@ -313,7 +534,7 @@ func (h *Handler) handleGameView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	view.Page = "games"
-	_ = h.tpl.ExecuteTemplate(w, "game_viewer.html", view)
+	h.render(w, "game_viewer.html", view)
 }
 
 type GameMoveView struct {
@@ -323,6 +544,7 @@ type GameMoveView struct {
 	Side      string
 	Log       string
 	ElapsedMS int64
+	PV        string
 }
 
 type GamePositionView struct {
@@ -331,16 +553,25 @@ type GamePositionView struct {
 	FEN   string
 }
 
+// games longer than this render only the start position; the viewer fetches
+// the other plies from /api/games/{id}/position.
+const gameViewFullPlies = 300
+
 type GameView struct {
 	ID          int64
 	PlayedAt    string
 	White       string
 	Black       string
 	MovetimeMS  int
+	SearchLimit string
+	Seed        int64
 	Result      string
 	Termination string
+	ECO         string
+	Opening     string
 	Moves       []GameMoveView
 	Positions   []GamePositionView
+	Lazy        bool
 	Page        string
 }
 
@@ -359,8 +590,10 @@ func buildGameView(game db.GameDetail, logByPly map[int]db.EngineLog) (GameView,
 		}
 	}
 
+	lazy := false
 	if strings.TrimSpace(game.MovesUCI) != "" {
 		parts := strings.Fields(game.MovesUCI)
+		lazy = len(parts) > gameViewFullPlies
 		for i, uci := range parts {
 			opt, err := chess.FEN(pos.String())
 			if err != nil {
@@ -373,6 +606,7 @@ func buildGameView(game db.GameDetail, logByPly map[int]db.EngineLog) (GameView,
 				break
 			}
 			san := chess.AlgebraicNotation{}.Encode(g.Position(), mv)
+			pv := pvSAN(g.Position(), logByPly[i+1].PV)
 			if err := g.Move(mv); err != nil {
 				break
 			}
@@ -382,8 +616,10 @@ func buildGameView(game db.GameDetail, logByPly map[int]db.EngineLog) (GameView,
 			if ply%2 == 0 {
 				side = "Black"
 			}
-			movesByPly[ply] = GameMoveView{Index: ply, UCI: uci, SAN: san, Side: side}
-			positions = append(positions, GamePositionView{Index: i + 1, Board: boardFromPosition(pos), FEN: pos.String()})
+			movesByPly[ply] = GameMoveView{Index: ply, UCI: uci, SAN: san, Side: side, PV: pv}
+			if !lazy {
+				positions = append(positions, GamePositionView{Index: i + 1, Board: boardFromPosition(pos), FEN: pos.String()})
+			}
 		}
 	}
 
@@ -418,26 +654,59 @@ func buildGameView(game db.GameDetail, logByPly map[int]db.EngineLog) (GameView,
 		White:       game.White,
 		Black:       game.Black,
 		MovetimeMS:  game.MovetimeMS,
+		SearchLimit: game.SearchLimit,
+		Seed:        game.Seed,
 		Result:      game.Result,
 		Termination: game.Termination,
+		ECO:         game.ECO,
+		Opening:     game.Opening,
 		Moves:       moves,
 		Positions:   positions,
+		Lazy:        lazy,
 	}, nil
 }
 
+// gameStatus tells finished games from those stored without a result, which
+// were either aborted or cut short, matching the status search filter.
+func gameStatus(result, termination string) string {
+	if result != "*" && result != "" {
+		return "Finished"
+	}
+	if termination == db.TerminationAborted {
+		return "Aborted"
+	}
+	return "Unfinished"
+}
+
+// pvSAN converts a stored UCI pv to SAN from pos, stopping at the first move
+// that doesn't replay.
+func pvSAN(pos *chess.Position, pv string) string {
+	var out []string
+	for _, raw := range strings.Fields(pv) {
+		var next *chess.Move
+		for _, mv := range pos.ValidMoves() {
+			if (chess.UCINotation{}).Encode(pos, mv) == raw {
+				next = mv
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		out = append(out, chess.AlgebraicNotation{}.Encode(pos, next))
+		pos = pos.Update(next)
+	}
+	return strings.Join(out, " ")
+}
+
 func (h *Handler) handleMatchupMoves(w http.ResponseWriter, r *http.Request) {
 	aIDStr := strings.TrimSpace(r.URL.Query().Get("a_id"))
 	bIDStr := strings.TrimSpace(r.URL.Query().Get("b_id"))
 	aName := strings.TrimSpace(r.URL.Query().Get("a"))
 	bName := strings.TrimSpace(r.URL.Query().Get("b"))
-	movetimeStr := strings.TrimSpace(r.URL.Query().Get("movetime"))
-	if movetimeStr == "" {
-		http.Error(w, "missing movetime", http.StatusBadRequest)
-		return
-	}
-	movetime, err := strconv.Atoi(movetimeStr)
+	searchLimit, err := matchupSearchLimit(r.URL.Query())
 	if err != nil {
-		http.Error(w, "invalid movetime", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -470,11 +739,6 @@ func (h *Handler) handleMatchupMoves(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing a_id/b_id", http.StatusBadRequest)
 		return
 	}
-	lines, err := h.store.MatchupMovesLines(r.Context(), aID, bID, movetime)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
 	if aName == "" {
 		if eng, err := h.store.EngineByID(r.Context(), aID); err == nil {
 			aName = eng.Name
@@ -491,10 +755,11 @@ func (h *Handler) handleMatchupMoves(w http.ResponseWriter, r *http.Request) {
 	if bName == "" {
 		bName = "engine"
 	}
-	filename := fmt.Sprintf("matchup-%s-vs-%s-%dms.txt", aName, bName, movetime)
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", sanitizeFilename(filename)))
-	_, _ = w.Write([]byte(lines))
+	limitLabel := strings.ReplaceAll(searchLimit, ":", "-")
+	filename := fmt.Sprintf("matchup-%s-vs-%s-%s.txt", aName, bName, limitLabel)
+	streamMovesLines(w, r, sanitizeFilename(filename), func(out io.Writer) error {
+		return h.store.WriteMatchupMovesLines(r.Context(), out, aID, bID, searchLimit)
+	})
 }
 
 func (h *Handler) handleMatchupDelete(w http.ResponseWriter, r *http.Request) {
@@ -504,14 +769,9 @@ func (h *Handler) handleMatchupDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	aIDStr := strings.TrimSpace(r.Form.Get("a_id"))
 	bIDStr := strings.TrimSpace(r.Form.Get("b_id"))
-	movetimeStr := strings.TrimSpace(r.Form.Get("movetime"))
-	if movetimeStr == "" {
-		http.Error(w, "missing movetime", http.StatusBadRequest)
-		return
-	}
-	movetime, err := strconv.Atoi(movetimeStr)
+	searchLimit, err := matchupSearchLimit(r.Form)
 	if err != nil {
-		http.Error(w, "invalid movetime", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	aID, err := strconv.ParseInt(aIDStr, 10, 64)
@@ -524,13 +784,115 @@ func (h *Handler) handleMatchupDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid b_id", http.StatusBadRequest)
 		return
 	}
-	if _, err := h.store.DeleteMatchupGames(r.Context(), aID, bID, movetime); err != nil {
+	aName, bName := fmt.Sprintf("engine %d", aID), fmt.Sprintf("engine %d", bID)
+	if eng, err := h.store.EngineByID(r.Context(), aID); err == nil {
+		aName = eng.Name
+	}
+	if eng, err := h.store.EngineByID(r.Context(), bID); err == nil {
+		bName = eng.Name
+	}
+	msg := fmt.Sprintf("Delete all games between %s and %s played with %s?", aName, bName, searchLimit)
+	if !h.requireConfirm(w, r, "games", "Delete games", msg, "/games") {
+		return
+	}
+	n, err := h.store.DeleteMatchupGames(r.Context(), aID, bID, searchLimit)
+	h.summaries.invalidate()
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, "delete games", fmt.Sprintf("%d games between %s and %s with %s", n, aName, bName, searchLimit))
 	http.Redirect(w, r, "/games", http.StatusSeeOther)
 }
 
+// parseOpeningPrefix turns "1.e4 c5" or "e2e4 c7c5" into space-separated UCI
+// moves, replaying them from the start position to validate them.
+func parseOpeningPrefix(raw string) (string, error) {
+	game := chess.NewGame()
+	moves := make([]string, 0, 8)
+	for _, token := range strings.Fields(raw) {
+		// drop move numbers like "1." or "1..."
+		token = strings.TrimLeft(token, "0123456789")
+		token = strings.TrimLeft(token, ".")
+		if token == "" {
+			continue
+		}
+		pos := game.Position()
+		mv, err := chess.UCINotation{}.Decode(pos, token)
+		if err != nil {
+			mv, err = chess.AlgebraicNotation{}.Decode(pos, token)
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid opening move %q", token)
+		}
+		if err := game.Move(mv); err != nil {
+			return "", fmt.Errorf("illegal opening move %q", token)
+		}
+		moves = append(moves, chess.UCINotation{}.Encode(pos, mv))
+	}
+	return strings.Join(moves, " "), nil
+}
+
+// parseSearchDate accepts RFC3339 or a plain date (UTC). A plain date used as
+// an upper bound includes that whole day.
+func parseSearchDate(raw string, upper bool) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC3339)", raw)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// matchupSearchLimit reads search_limit, falling back to the older movetime parameter.
+func matchupSearchLimit(values url.Values) (string, error) {
+	raw := strings.TrimSpace(values.Get("search_limit"))
+	if raw == "" {
+		movetimeStr := strings.TrimSpace(values.Get("movetime"))
+		if movetimeStr == "" {
+			return "", fmt.Errorf("missing search_limit")
+		}
+		movetime, err := strconv.Atoi(movetimeStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid movetime")
+		}
+		return engine.SearchLimit{Mode: engine.LimitMovetime, Value: movetime}.String(), nil
+	}
+	limit, err := engine.ParseSearchLimit(raw)
+	if err != nil {
+		return "", err
+	}
+	return limit.String(), nil
+}
+
+// order limits by mode, then numerically by value
+func searchLimitLess(a, b string) bool {
+	la, errA := engine.ParseSearchLimit(a)
+	lb, errB := engine.ParseSearchLimit(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	if la.Mode != lb.Mode {
+		return la.Mode < lb.Mode
+	}
+	return la.Value < lb.Value
+}
+
 func sanitizeFilename(name string) string {
 	name = strings.ReplaceAll(name, " ", "_")
 	name = strings.ReplaceAll(name, "/", "-")
@@ -552,11 +914,7 @@ func (h *Handler) handleGameMoves(w http.ResponseWriter, r *http.Request) {
 	}
 	moves, result, err := h.store.GameMoves(r.Context(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.NotFound(w, r)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		storeError(w, err, "unknown game")
 		return
 	}
 	line := moves
@@ -564,7 +922,72 @@ func (h *Handler) handleGameMoves(w http.ResponseWriter, r *http.Request) {
 		line += " "
 	}
 	line += result
+	// stored games never change, so the content identifies the response
+	sum := sha256.Sum256([]byte(strconv.FormatInt(id, 10) + "\n" + line))
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=game-%d.txt", id))
 	_, _ = w.Write([]byte(line + "\n"))
 }
+
+// /api/games/{id}/position?ply=N replays the game up to ply N (default 0) and
+// returns that position.
+func (h *Handler) handleGamePositionJSON(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	ply := 0
+	if raw := strings.TrimSpace(r.URL.Query().Get("ply")); raw != "" {
+		ply, err = strconv.Atoi(raw)
+		if err != nil || ply < 0 {
+			http.Error(w, "invalid ply", http.StatusBadRequest)
+			return
+		}
+	}
+	movesUCI, _, err := h.store.GameMoves(r.Context(), id)
+	if err != nil {
+		storeError(w, err, "unknown game")
+		return
+	}
+	moves := strings.Fields(movesUCI)
+	if ply > len(moves) {
+		http.Error(w, fmt.Sprintf("ply out of range (game has %d plies)", len(moves)), http.StatusBadRequest)
+		return
+	}
+
+	game := chess.NewGame()
+	n := chess.UCINotation{}
+	lastUCI, lastSAN := "", ""
+	for _, uci := range moves[:ply] {
+		mv, err := n.Decode(game.Position(), uci)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid move %q in game", uci), http.StatusInternalServerError)
+			return
+		}
+		lastSAN = chess.AlgebraicNotation{}.Encode(game.Position(), mv)
+		lastUCI = uci
+		if err := game.Move(mv); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	pos := game.Position()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"game_id": id,
+		"ply":     ply,
+		"plies":   len(moves),
+		"fen":     pos.String(),
+		"move":    lastUCI,
+		"san":     lastSAN,
+		"board":   boardFromPosition(pos),
+	})
+}
@@ -3,11 +3,15 @@ package web
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
 )
 
 func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	gameCount, err := h.store.CountGames(ctx)
+	gameCount, err := h.store.CachedGameCount(ctx)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -32,7 +36,7 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.tpl.ExecuteTemplate(w, "live_view.html", map[string]any{
+	h.render(w, "live_view.html", map[string]any{
 		"Page":        "live",
 		"GameCount":   gameCount,
 		"EngineCount": engineCount,
@@ -44,20 +48,25 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleLiveFragment(w http.ResponseWriter, r *http.Request) {
 	live := h.r.Live()
-	_ = h.tpl.ExecuteTemplate(w, "live_fragment.html", live)
+	h.render(w, "live_fragment.html", live)
 }
 
 func (h *Handler) handleLiveJSON(w http.ResponseWriter, r *http.Request) {
 	live := h.r.Live()
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"status":      live.Status,
-		"white":       live.White,
-		"black":       live.Black,
-		"movetime_ms": live.MovetimeMS,
-		"result":      live.Result,
-		"fen":         live.FEN,
-		"moves_uci":   live.MovesUCI,
+		"status":       live.Status,
+		"white":        live.White,
+		"black":        live.Black,
+		"movetime_ms":  live.MovetimeMS,
+		"search_limit": live.Limit,
+		"seed":         live.Seed,
+		"result":       live.Result,
+		"fen":          live.FEN,
+		"moves_uci":    live.MovesUCI,
+		"moves_san":    strings.Fields(pvSAN(chess.StartingPosition(), strings.Join(live.MovesUCI, " "))),
+		"ply":          len(live.MovesUCI),
+		"eval_cp":      live.EvalCP,
 	})
 }
 
@@ -73,7 +82,7 @@ func (h *Handler) handleQueueFragment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.tpl.ExecuteTemplate(w, "queue_fragment.html", map[string]any{
+	h.render(w, "queue_fragment.html", map[string]any{
 		"QueueCount": queueCount,
 		"Queue":      queue,
 	})
@@ -86,7 +95,42 @@ func (h *Handler) handleRecentGamesFragment(w http.ResponseWriter, r *http.Reque
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.tpl.ExecuteTemplate(w, "recent_games_fragment.html", map[string]any{
+	h.render(w, "recent_games_fragment.html", map[string]any{
 		"RecentGames": recentGames,
 	})
 }
+
+func (h *Handler) handleLiveAllJSON(w http.ResponseWriter, r *http.Request) {
+	boards := h.r.Boards()
+	out := make([]map[string]any, 0, len(boards))
+	now := time.Now()
+	for i, live := range boards {
+		elapsedMS := int64(0)
+		startedAt := ""
+		if !live.StartedAt.IsZero() {
+			startedAt = live.StartedAt.UTC().Format(time.RFC3339)
+			end := now
+			if live.Status == "finished" {
+				end = live.UpdatedAt
+			}
+			elapsedMS = end.Sub(live.StartedAt).Milliseconds()
+		}
+		out = append(out, map[string]any{
+			"board":        i,
+			"game_id":      live.GameID,
+			"status":       live.Status,
+			"white":        live.White,
+			"black":        live.Black,
+			"white_id":     live.WhiteID,
+			"black_id":     live.BlackID,
+			"search_limit": live.Limit,
+			"result":       live.Result,
+			"fen":          live.FEN,
+			"moves_uci":    live.MovesUCI,
+			"started_at":   startedAt,
+			"elapsed_ms":   elapsedMS,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"boards": out})
+}
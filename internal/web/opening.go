@@ -11,20 +11,24 @@ import (
 )
 
 type OpeningNode struct {
-	Move       string
-	Count      int
-	WhiteWins  int
-	BlackWins  int
-	Draws      int
-	Children   []*OpeningNode
+	Move       string         `json:"move"`
+	Count      int            `json:"count"`
+	WhiteWins  int            `json:"white_wins"`
+	BlackWins  int            `json:"black_wins"`
+	Draws      int            `json:"draws"`
+	Children   []*OpeningNode `json:"children,omitempty"`
 	childrenBy map[string]*OpeningNode
+
+	// Score is White's share of the points from 0 to 1, draws counting
+	// half; 0.5 when no game through the node has a result.
+	Score float64 `json:"score"`
 }
 
 type OpeningTree struct {
-	MaxPlies int
-	MinCount int
-	Games    int
-	Root     *OpeningNode
+	MaxPlies int          `json:"max_plies"`
+	MinCount int          `json:"min_count"`
+	Games    int          `json:"games"`
+	Root     *OpeningNode `json:"root"`
 }
 
 type gameMoves struct {
@@ -91,7 +95,16 @@ func (n *OpeningNode) child(move string) *OpeningNode {
 	return child
 }
 
+// ScorePct is Score as a percentage.
+func (n *OpeningNode) ScorePct() float64 {
+	return n.Score * 100
+}
+
 func (n *OpeningNode) finalize() {
+	n.Score = 0.5
+	if games := n.WhiteWins + n.Draws + n.BlackWins; games > 0 {
+		n.Score = (float64(n.WhiteWins) + float64(n.Draws)/2) / float64(games)
+	}
 	if len(n.Children) == 0 {
 		return
 	}
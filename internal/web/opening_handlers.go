@@ -1,28 +1,116 @@
 package web
 
-import "net/http"
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tethys/internal/db"
+)
 
 func (h *Handler) handleOpeningPage(w http.ResponseWriter, r *http.Request) {
-	_ = h.tpl.ExecuteTemplate(w, "opening_explorer.html", map[string]any{
-		"Page": "opening",
+	hist, err := h.store.BookPlyHistogram(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.render(w, "opening_explorer.html", map[string]any{
+		"Page":         "opening",
+		"BookCoverage": buildBookCoverage(hist),
 	})
 }
 
+type BookPlyRow struct {
+	Ply     int
+	Games   int
+	Percent float64 // share of all finished games
+	Width   float64 // bar width relative to the largest bucket
+}
+
+type BookCoverageView struct {
+	Games   int
+	Average float64
+	Rows    []BookPlyRow
+}
+
+// buildBookCoverage turns the book-ply histogram into bar rows and the
+// average number of book plies per game.
+func buildBookCoverage(hist []db.BookPlyCount) BookCoverageView {
+	var view BookCoverageView
+	maxCount, plies := 0, 0
+	for _, row := range hist {
+		view.Games += row.Count
+		plies += row.BookPlies * row.Count
+		maxCount = max(maxCount, row.Count)
+	}
+	if view.Games == 0 {
+		return view
+	}
+	view.Average = float64(plies) / float64(view.Games)
+	for _, row := range hist {
+		view.Rows = append(view.Rows, BookPlyRow{
+			Ply:     row.BookPlies,
+			Games:   row.Count,
+			Percent: 100 * float64(row.Count) / float64(view.Games),
+			Width:   100 * float64(row.Count) / float64(maxCount),
+		})
+	}
+	return view
+}
+
+const (
+	openingMaxPlies = 16
+	openingMaxGames = 2000
+	// hard cap for API callers; the tree grows with plies * games
+	openingPlyLimit = 40
+)
+
 func (h *Handler) handleOpeningFragment(w http.ResponseWriter, r *http.Request) {
-	const (
-		maxPlies = 16
-		maxGames = 2000
-	)
 	conf, err := h.store.GetSettings(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	opening, err := buildOpeningTree(r.Context(), h.store, maxPlies, maxGames, conf.OpeningMin)
+	opening, err := buildOpeningTree(r.Context(), h.store, openingMaxPlies, openingMaxGames, conf.OpeningMin)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.render(w, "opening_fragment.html", opening)
+}
+
+// /api/opening?max_plies=&min_count= returns the opening tree as JSON.
+func (h *Handler) handleOpeningJSON(w http.ResponseWriter, r *http.Request) {
+	conf, err := h.store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	maxPlies := openingMaxPlies
+	if raw := strings.TrimSpace(r.URL.Query().Get("max_plies")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			http.Error(w, "invalid max_plies", http.StatusBadRequest)
+			return
+		}
+		maxPlies = min(v, openingPlyLimit)
+	}
+	minCount := conf.OpeningMin
+	if raw := strings.TrimSpace(r.URL.Query().Get("min_count")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid min_count", http.StatusBadRequest)
+			return
+		}
+		minCount = v
+	}
+	opening, err := buildOpeningTree(r.Context(), h.store, maxPlies, openingMaxGames, minCount)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = h.tpl.ExecuteTemplate(w, "opening_fragment.html", opening)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(opening)
 }
@@ -3,6 +3,7 @@ package web
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -22,6 +23,7 @@ type PositionEvalResponse struct {
 	EngineID   int64  `json:"engine_id"`
 	Depth      int    `json:"depth"`
 	Done       bool   `json:"done"`
+	Queued     bool   `json:"queued"`
 	Error      string `json:"error"`
 }
 
@@ -51,10 +53,13 @@ func (h *Handler) handlePositionView(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid zobrist", http.StatusBadRequest)
 			return
 		}
-		if cached, err := h.store.EvalByZobrist(ctx, key); err == nil {
-			fenKey = cached.FEN
-			fullFen = cached.FEN + " 0 1"
+		cached, err := h.store.EvalByZobrist(ctx, key)
+		if err != nil {
+			storeError(w, err, "no cached evaluation for this zobrist key")
+			return
 		}
+		fenKey = cached.FEN
+		fullFen = cached.FEN + " 0 1"
 	} else {
 		start := chess.StartingPosition()
 		fullFen = start.String()
@@ -87,13 +92,18 @@ func (h *Handler) handlePositionView(w http.ResponseWriter, r *http.Request) {
 			engineName = e.Name
 		}
 	}
-	_ = h.tpl.ExecuteTemplate(w, "position_view.html", map[string]any{
-		"Page":       "positions",
-		"FEN":        fenKey,
-		"ZobristKey": key,
-		"Board":      boardFromPosition(pos),
-		"Eval":       info,
-		"EngineName": engineName,
+	analysisMissing := false
+	if cfg, err := h.store.GetSettings(ctx); err == nil {
+		analysisMissing = h.analysisEngineMissing(ctx, cfg)
+	}
+	h.render(w, "position_view.html", map[string]any{
+		"Page":                  "positions",
+		"FEN":                   fenKey,
+		"ZobristKey":            key,
+		"Board":                 boardFromPosition(pos),
+		"Eval":                  info,
+		"EngineName":            engineName,
+		"AnalysisEngineMissing": analysisMissing,
 	})
 }
 
@@ -111,10 +121,13 @@ func (h *Handler) handlePositionEval(w http.ResponseWriter, r *http.Request) {
 	}
 	info, ok := h.an.Latest(key)
 	if !ok {
-		if cached, err := h.store.EvalByZobrist(ctx, key); err == nil {
+		// a position nobody analyzed yet is a normal answer: an empty eval
+		cached, err := h.store.EvalByZobrist(ctx, key)
+		if err == nil {
 			info = engineToAnalysisInfo(cached)
-		} else if err != sql.ErrNoRows {
-			w.WriteHeader(http.StatusNotFound)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -125,6 +138,7 @@ func (h *Handler) handlePositionEval(w http.ResponseWriter, r *http.Request) {
 		EngineID:   info.EngineID,
 		Depth:      info.Depth,
 		Done:       info.Done,
+		Queued:     info.Queued,
 		Error:      info.Err,
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -132,15 +146,24 @@ func (h *Handler) handlePositionEval(w http.ResponseWriter, r *http.Request) {
 }
 
 type PositionMoveResponse struct {
-	FEN        string `json:"fen"`
-	ZobristKey uint64 `json:"zobrist_key"`
+	FEN        string           `json:"fen"`
+	ZobristKey uint64           `json:"zobrist_key"`
+	LegalMoves []LegalMoveEntry `json:"legal_moves"`
+}
+
+type LegalMoveEntry struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
 }
 
+// /api/positions/move plays ?uci= or ?san= from ?fen= and returns the new
+// position with its legal moves. Without a move it describes fen itself.
 func (h *Handler) handlePositionMove(w http.ResponseWriter, r *http.Request) {
 	fenParam := strings.TrimSpace(r.URL.Query().Get("fen"))
 	uci := strings.TrimSpace(r.URL.Query().Get("uci"))
-	if fenParam == "" || uci == "" {
-		http.Error(w, "missing fen or uci", http.StatusBadRequest)
+	san := strings.TrimSpace(r.URL.Query().Get("san"))
+	if fenParam == "" {
+		http.Error(w, "missing fen", http.StatusBadRequest)
 		return
 	}
 	_, fullFen, err := normalizeFENForView(fenParam)
@@ -154,15 +177,21 @@ func (h *Handler) handlePositionMove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	game := chess.NewGame(opt)
-	n := chess.UCINotation{}
-	mv, err := n.Decode(game.Position(), uci)
-	if err != nil {
-		http.Error(w, "invalid move", http.StatusBadRequest)
-		return
-	}
-	if err := game.Move(mv); err != nil {
-		http.Error(w, "illegal move", http.StatusBadRequest)
-		return
+	if uci != "" || san != "" {
+		var mv *chess.Move
+		if uci != "" {
+			mv, err = chess.UCINotation{}.Decode(game.Position(), uci)
+		} else {
+			mv, err = chess.AlgebraicNotation{}.Decode(game.Position(), san)
+		}
+		if err != nil {
+			http.Error(w, "invalid move", http.StatusBadRequest)
+			return
+		}
+		if err := game.Move(mv); err != nil {
+			http.Error(w, "illegal move", http.StatusBadRequest)
+			return
+		}
 	}
 	pos := game.Position()
 	fenKey, _, err := normalizeFENForView(pos.String())
@@ -175,11 +204,103 @@ func (h *Handler) handlePositionMove(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	resp := PositionMoveResponse{FEN: fenKey, ZobristKey: key}
+	resp := PositionMoveResponse{FEN: fenKey, ZobristKey: key, LegalMoves: legalMoves(pos)}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// /api/position/moves?fen= lists the legal moves of a position.
+func (h *Handler) handlePositionLegalMoves(w http.ResponseWriter, r *http.Request) {
+	fenParam := strings.TrimSpace(r.URL.Query().Get("fen"))
+	if fenParam == "" {
+		http.Error(w, "missing fen", http.StatusBadRequest)
+		return
+	}
+	_, fullFen, err := normalizeFENForView(fenParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pos, err := positionFromFEN(fullFen)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(legalMoves(pos))
+}
+
+const (
+	positionGamesDefault = 50
+	positionGamesMax     = 500
+)
+
+// /api/positions/games?zobrist=|fen=&limit= lists games that passed through
+// a position. This replays stored games, so it is kept apart from the main
+// game search.
+func (h *Handler) handlePositionGames(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var key uint64
+	if raw := strings.TrimSpace(q.Get("zobrist")); raw != "" {
+		key, _ = strconv.ParseUint(raw, 10, 64)
+	} else if fen := strings.TrimSpace(q.Get("fen")); fen != "" {
+		_, full, err := normalizeFENForView(fen)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key, _ = zobristFromFEN(full)
+	}
+	if key == 0 {
+		http.Error(w, "missing or invalid zobrist/fen", http.StatusBadRequest)
+		return
+	}
+	limit := positionGamesDefault
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(v, positionGamesMax)
+	}
+
+	matches, err := h.store.GamesContainingPosition(r.Context(), key, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	games := make([]map[string]any, 0, len(matches))
+	for _, m := range matches {
+		games = append(games, map[string]any{
+			"id":          m.ID,
+			"played_at":   m.PlayedAt,
+			"white":       m.White,
+			"black":       m.Black,
+			"result":      m.Result,
+			"termination": m.Termination,
+			"ply":         m.Ply,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"zobrist": key,
+		"games":   games,
+	})
+}
+
+func legalMoves(pos *chess.Position) []LegalMoveEntry {
+	valid := pos.ValidMoves()
+	out := make([]LegalMoveEntry, 0, len(valid))
+	for _, mv := range valid {
+		out = append(out, LegalMoveEntry{
+			UCI: chess.UCINotation{}.Encode(pos, mv),
+			SAN: chess.AlgebraicNotation{}.Encode(pos, mv),
+		})
+	}
+	return out
+}
+
 func normalizeFENForView(fen string) (string, string, error) {
 	parts := strings.Fields(strings.TrimSpace(fen))
 	if len(parts) < 4 {
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// storeError answers a failed lookup: 404 with notFound if the row doesn't
+// exist, 500 for a database error.
+func storeError(w http.ResponseWriter, err error, notFound string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// render executes a template into a buffer first, so a template error turns
+// into a logged 500 instead of a half-written page.
+func (h *Handler) render(w http.ResponseWriter, name string, data any) {
+	tpl, err := h.templates()
+	if err != nil {
+		log.Printf("render %s: %v", name, err)
+		http.Error(w, "internal error rendering page", http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := tpl.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Printf("render %s: %v", name, err)
+		http.Error(w, "internal error rendering page", http.StatusInternalServerError)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("render %s: write: %v", name, err)
+	}
+}
@@ -3,17 +3,26 @@ package web
 import (
 	"math"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 
 	"tethys/internal/db"
 	"tethys/internal/ranking"
 )
 
 type RankingRow struct {
-	Rank  int
-	Name  string
-	Elo   float64
-	Games int
+	ID     int64
+	Rank   int
+	Name   string
+	Elo    float64
+	Games  int
+	Points float64
+	// AvgOppElo is the games-weighted mean Elo of the engine's opponents
+	AvgOppElo float64
+	Notes     string
 }
 
 type MatchupBreakdown struct {
@@ -22,6 +31,7 @@ type MatchupBreakdown struct {
 	Losses           int
 	Draws            int
 	Total            int
+	Points           float64
 	WinPct           float64
 	LossPct          float64
 	DrawPct          float64
@@ -37,6 +47,12 @@ type RankingView struct {
 }
 
 func (h *Handler) handleResults(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scoring := ranking.ScoringFromSettings(cfg.RankingScoring, cfg.RankingDrawScore)
 	engines, err := h.store.ListEngines(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -65,56 +81,169 @@ func (h *Handler) handleResults(w http.ResponseWriter, r *http.Request) {
 			return engines[i].Name < engines[j].Name
 		})
 	}
+	tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	tags := collectTags(engines)
+	inView := make(map[string]bool, len(engines))
+	for _, eng := range engines {
+		if tag == "" || eng.HasTag(tag) {
+			inView[eng.Name] = true
+		}
+	}
 	matchupsByEngine := buildMatchupsByEngine(rows)
 	gamesByEngine := buildGamesByEngine(rows)
 	eloByName := make(map[string]float64, len(engines))
+	eloByID := make(map[int64]float64, len(engines))
 	for _, eng := range engines {
 		eloByName[eng.Name] = eng.Elo
+		if eng.Elo > 0 {
+			eloByID[eng.ID] = eng.Elo
+		}
 	}
+	avgOppElo := ranking.AverageOpponentElos(rows, eloByID)
 	view := make([]RankingView, 0, len(engines))
 	for i, eng := range engines {
+		if !inView[eng.Name] {
+			continue
+		}
 		matchups := matchupsByEngine[eng.Name]
-		for j := range matchups {
-			oppElo := eloByName[matchups[j].Opponent]
-			deltaElo := eng.Elo - oppElo
-			expected := 100.0 / (1.0 + math.Pow(10.0, -deltaElo/400.0))
-			actual := 0.0
-			if matchups[j].Total > 0 {
-				actual = (float64(matchups[j].Wins) + 0.5*float64(matchups[j].Draws)) * 100.0 / float64(matchups[j].Total)
+		if tag != "" {
+			// only show games among the tagged engines
+			filtered := matchups[:0]
+			for _, m := range matchups {
+				if inView[m.Opponent] {
+					filtered = append(filtered, m)
+				}
 			}
-			matchups[j].ExpectedScorePct = expected
-			matchups[j].ActualScorePct = actual
-			matchups[j].DeltaScorePct = actual - expected
-			matchups[j].EloDiff = deltaElo
+			matchups = filtered
 		}
-		sort.Slice(matchups, func(i, j int) bool {
-			eloI := eloByName[matchups[i].Opponent]
-			eloJ := eloByName[matchups[j].Opponent]
-			if eloI == eloJ {
-				return matchups[i].Opponent < matchups[j].Opponent
-			}
-			return eloI > eloJ
-		})
+		points := scoreMatchups(eng, matchups, eloByName, scoring)
 		view = append(view, RankingView{RankingRow: RankingRow{
-			Rank:  i + 1,
-			Name:  eng.Name,
-			Elo:   eng.Elo,
-			Games: gamesByEngine[eng.Name],
+			ID:        eng.ID,
+			Rank:      i + 1,
+			Name:      eng.Name,
+			Elo:       eng.Elo,
+			Games:     gamesByEngine[eng.Name],
+			Points:    points,
+			AvgOppElo: avgOppElo[eng.ID],
+			Notes:     eng.Notes,
 		}, Matchups: matchups})
 	}
-	_ = h.tpl.ExecuteTemplate(w, "ranking.html", map[string]any{
+	h.render(w, "ranking.html", map[string]any{
 		"Rankings": view,
+		"Scoring":  scoring,
+		"Tags":     tags,
+		"Tag":      tag,
 		"Page":     "ranking",
 	})
 }
 
+// scoreMatchups fills in the points and expected scores of an engine's
+// matchups, sorts them strongest opponent first and returns the total points.
+func scoreMatchups(eng db.Engine, matchups []MatchupBreakdown, eloByName map[string]float64, scoring ranking.Scoring) float64 {
+	points := 0.0
+	for j := range matchups {
+		matchups[j].Points = scoring.Points(matchups[j].Wins, matchups[j].Draws, matchups[j].Losses)
+		points += matchups[j].Points
+		oppElo := eloByName[matchups[j].Opponent]
+		deltaElo := eng.Elo - oppElo
+		expected := 100.0 / (1.0 + math.Pow(10.0, -deltaElo/400.0))
+		actual := 0.0
+		if matchups[j].Total > 0 {
+			actual = (float64(matchups[j].Wins) + scoring.DrawFraction()*float64(matchups[j].Draws)) * 100.0 / float64(matchups[j].Total)
+		}
+		matchups[j].ExpectedScorePct = expected
+		matchups[j].ActualScorePct = actual
+		matchups[j].DeltaScorePct = actual - expected
+		matchups[j].EloDiff = deltaElo
+	}
+	sort.Slice(matchups, func(i, j int) bool {
+		eloI := eloByName[matchups[i].Opponent]
+		eloJ := eloByName[matchups[j].Opponent]
+		if eloI == eloJ {
+			return matchups[i].Opponent < matchups[j].Opponent
+		}
+		return eloI > eloJ
+	})
+	return points
+}
+
+// engineRecentGames is how many games the engine page lists.
+const engineRecentGames = 20
+
+// handleEnginePage shows one engine: its configuration, matchups, rating
+// history and recent games.
+func (h *Handler) handleEnginePage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	ctx := r.Context()
+	eng, err := h.store.EngineByID(ctx, id)
+	if err != nil {
+		storeError(w, err, "unknown engine")
+		return
+	}
+	cfg, err := h.store.GetSettings(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scoring := ranking.ScoringFromSettings(cfg.RankingScoring, cfg.RankingDrawScore)
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rows, err := h.store.ResultsByPair(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	games, err := h.store.RecentGamesForEngine(ctx, id, engineRecentGames)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	history, err := h.store.EloHistory(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	eloByName := make(map[string]float64, len(engines))
+	for _, e := range engines {
+		eloByName[e.Name] = e.Elo
+	}
+	matchups := buildMatchupsByEngine(rows)[eng.Name]
+	points := scoreMatchups(eng, matchups, eloByName, scoring)
+	// newest rating first
+	slices.Reverse(history)
+	h.render(w, "engine.html", map[string]any{
+		"Engine":      eng,
+		"Binary":      filepath.Base(eng.Path),
+		"Games":       buildGamesByEngine(rows)[eng.Name],
+		"Points":      points,
+		"Matchups":    matchups,
+		"EloHistory":  history,
+		"RecentGames": games,
+		"Scoring":     scoring,
+		"Page":        "ranking",
+	})
+}
+
 func (h *Handler) handleRankingRecompute(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.store.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	rows, err := h.store.ResultsByPair(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	elos := ranking.ComputeBradleyTerryElos(rows, 3600)
+	scoring := ranking.ScoringFromSettings(cfg.RankingScoring, cfg.RankingDrawScore)
+	elos := ranking.ComputeBradleyTerryElos(rows, 3600, scoring)
 	if err := h.store.ReplaceEngineElos(r.Context(), elos); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -156,6 +285,21 @@ func buildMatchupsByEngine(rows []db.PairResult) map[string][]MatchupBreakdown {
 	return matchups
 }
 
+func collectTags(engines []db.Engine) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, eng := range engines {
+		for _, t := range eng.TagList() {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
 func buildGamesByEngine(rows []db.PairResult) map[string]int {
 	games := make(map[string]int)
 	for _, row := range rows {
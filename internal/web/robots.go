@@ -0,0 +1,25 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultRobotsDisallow keeps crawlers off the admin pages and the bulk
+// downloads, which are expensive to generate.
+var DefaultRobotsDisallow = []string{"/download/", "/admin/"}
+
+func (h *Handler) handleRobots(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if len(h.robotsDisallow) == 0 {
+		// an empty Disallow allows everything
+		b.WriteString("Disallow:\n")
+	}
+	for _, path := range h.robotsDisallow {
+		fmt.Fprintf(&b, "Disallow: %s\n", path)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
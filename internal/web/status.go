@@ -0,0 +1,138 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"tethys/internal/engine"
+)
+
+// /api/status summarizes the scheduler for monitoring. It is public like the
+// other /api/live endpoints and cheap enough to poll.
+func (h *Handler) handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	games, err := h.store.CachedGameCount(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	queued, err := h.store.GameQueueSize(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cfg, err := h.store.GetSettings(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	scheduler := "stopped"
+	if h.r.Running() {
+		scheduler = "running"
+	}
+	boards := h.r.Boards()
+	playing := 0
+	for _, b := range boards {
+		if b.Status == "running" {
+			playing++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"scheduler":      scheduler,
+		"boards":         len(boards),
+		"boards_playing": playing,
+		"games":          games,
+		"engines":        len(engines),
+		"pairs":          engine.PairCount(engines, cfg.MatchAllowMirror),
+		"queued_games":   queued,
+		"started_at":     h.started.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(h.started).Seconds()),
+	})
+}
+
+// /readyz answers 200 while the scheduler runs and has at least one pair of
+// engines to queue games for, and 503 with the reason otherwise, so
+// monitoring can tell a misconfigured instance from one that is merely
+// between games.
+func (h *Handler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cfg, err := h.store.GetSettings(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	eligible := 0
+	for _, e := range engines {
+		if engine.IneligibleReason(e) == "" {
+			eligible++
+		}
+	}
+	pairs := engine.PairCount(engines, cfg.MatchAllowMirror)
+	reason := ""
+	switch {
+	case !h.r.Running():
+		reason = "scheduler stopped"
+	case eligible == 0:
+		reason = "no engines that can play: add engines or resume paused ones"
+	case pairs == 0:
+		reason = "one engine can play and mirror matches are off: add an engine or allow mirror matches"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	if reason != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"ready":   reason == "",
+		"reason":  reason,
+		"engines": eligible,
+		"pairs":   pairs,
+	})
+}
+
+// /api/engines lists the engines with their game counts and total thinking
+// time.
+func (h *Handler) handleEnginesJSON(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	engines, err := h.store.ListEngines(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	gameCounts, err := h.store.EngineGameCounts(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	computeMS, err := h.store.EngineComputeTime(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]map[string]any, 0, len(engines))
+	for _, e := range engines {
+		out = append(out, map[string]any{
+			"id":         e.ID,
+			"name":       e.Name,
+			"elo":        e.Elo,
+			"games":      gameCounts[e.ID],
+			"compute_ms": computeMS[e.ID],
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,220 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+
+	"tethys/internal/db"
+)
+
+type SuiteView struct {
+	db.SuiteSummary
+	Running bool
+	// SolvedPct is the share of bm positions solved by the last run
+	SolvedPct float64
+}
+
+type SuitePositionView struct {
+	db.SuitePosition
+	BestSAN  string
+	FoundSAN string
+	Solved   bool
+}
+
+func newSuiteView(s db.SuiteSummary, running bool) SuiteView {
+	view := SuiteView{SuiteSummary: s, Running: running}
+	if s.WithBM > 0 {
+		view.SolvedPct = float64(s.Solved) * 100 / float64(s.WithBM)
+	}
+	return view
+}
+
+func (h *Handler) handleAdminSuites(w http.ResponseWriter, r *http.Request) {
+	files, err := listBookOptions(h.suitesDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summaries, err := h.store.ListSuites(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	engines, err := h.store.ListEngines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	suites := make([]SuiteView, 0, len(summaries))
+	for _, s := range summaries {
+		suites = append(suites, newSuiteView(s, h.an.SuiteRunning(s.Suite)))
+	}
+	h.render(w, "suites.html", map[string]any{
+		"Files":     files,
+		"Suites":    suites,
+		"Engines":   engines,
+		"SuitesDir": h.suitesDir,
+		"Page":      "suites",
+	})
+}
+
+type SuiteRunView struct {
+	db.SuiteRun
+	Rank      int
+	SolvedPct float64
+}
+
+// handleSuiteResults shows each engine's best run of every test suite.
+func (h *Handler) handleSuiteResults(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.store.SuiteLeaderboard(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var suites []string
+	bySuite := make(map[string][]SuiteRunView)
+	for _, run := range runs {
+		view := SuiteRunView{SuiteRun: run}
+		if run.Total > 0 {
+			view.SolvedPct = float64(run.Solved) * 100 / float64(run.Total)
+		}
+		if _, ok := bySuite[run.Suite]; !ok {
+			suites = append(suites, run.Suite)
+		}
+		view.Rank = len(bySuite[run.Suite]) + 1
+		bySuite[run.Suite] = append(bySuite[run.Suite], view)
+	}
+	h.render(w, "suite_results.html", map[string]any{
+		"Suites":  suites,
+		"BySuite": bySuite,
+		"Page":    "suite_results",
+	})
+}
+
+func (h *Handler) handleAdminSuiteImport(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.Form.Get("file"))
+	files, err := listBookOptions(h.suitesDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !slices.Contains(files, name) {
+		http.Error(w, "invalid suite file", http.StatusBadRequest)
+		return
+	}
+	if h.an.SuiteRunning(name) {
+		http.Error(w, "suite is running", http.StatusConflict)
+		return
+	}
+	f, err := os.Open(filepath.Join(h.suitesDir, name))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	positions, err := db.ParseEPD(f)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s: %v", name, err), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.ImportSuite(r.Context(), name, positions); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, "import suite", fmt.Sprintf("%s (%d positions)", name, len(positions)))
+	http.Redirect(w, r, "/admin/suites", http.StatusSeeOther)
+}
+
+func (h *Handler) handleAdminSuiteRun(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	suite := strings.TrimSpace(r.Form.Get("suite"))
+	// empty runs the analysis engine
+	var engineID int64
+	if v := strings.TrimSpace(r.Form.Get("engine_id")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid engine id", http.StatusBadRequest)
+			return
+		}
+		engineID = id
+	}
+	positions, err := h.store.SuitePositions(r.Context(), suite)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(positions) == 0 {
+		http.Error(w, "unknown suite", http.StatusNotFound)
+		return
+	}
+	if err := h.an.StartSuite(r.Context(), suite, engineID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if engineID > 0 {
+		h.audit(r, "run suite", fmt.Sprintf("%s with engine #%d", suite, engineID))
+	} else {
+		h.audit(r, "run suite", suite)
+	}
+	http.Redirect(w, r, "/admin/suites/view?suite="+url.QueryEscape(suite), http.StatusSeeOther)
+}
+
+func (h *Handler) handleAdminSuiteView(w http.ResponseWriter, r *http.Request) {
+	suite := strings.TrimSpace(r.URL.Query().Get("suite"))
+	positions, err := h.store.SuitePositions(r.Context(), suite)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(positions) == 0 {
+		http.Error(w, "unknown suite", http.StatusNotFound)
+		return
+	}
+	var summary db.SuiteSummary
+	rows := make([]SuitePositionView, 0, len(positions))
+	for _, p := range positions {
+		view := SuitePositionView{SuitePosition: p, Solved: p.Solved()}
+		if opt, err := chess.FEN(p.FEN); err == nil {
+			pos := chess.NewGame(opt).Position()
+			var best []string
+			for _, m := range strings.Fields(p.BestMoves) {
+				best = append(best, pvSAN(pos, m))
+			}
+			view.BestSAN = strings.Join(best, " ")
+			view.FoundSAN = pvSAN(pos, p.FoundMove)
+		}
+		rows = append(rows, view)
+
+		summary.Positions++
+		if p.BestMoves != "" {
+			summary.WithBM++
+		}
+		if p.FoundMove != "" {
+			summary.Run++
+		}
+		if view.Solved {
+			summary.Solved++
+		}
+	}
+	summary.Suite = suite
+	h.render(w, "suite_view.html", map[string]any{
+		"Suite":     newSuiteView(summary, h.an.SuiteRunning(suite)),
+		"Positions": rows,
+		"Page":      "suites",
+	})
+}
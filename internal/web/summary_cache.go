@@ -0,0 +1,79 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"tethys/internal/db"
+)
+
+// summaryTTL bounds how stale the games page summaries get: games finished
+// since the last load show up within this long, or at once after a refresh.
+// Deleting games, renaming or merging engines drops the cache right away.
+const summaryTTL = 30 * time.Second
+
+// summaryCache keeps the GROUP BY summaries behind the games page, which
+// scan the whole games table, in memory for summaryTTL.
+type summaryCache struct {
+	mu sync.Mutex
+	// indexed by whether matchups are split by color
+	entries [2]summaryEntry
+}
+
+type summaryEntry struct {
+	matchups []db.MatchupSummary
+	results  []db.ResultSummary
+	at       time.Time
+}
+
+// gameSummaries returns the matchup and result summaries and when they were
+// computed, reloading them once they are older than summaryTTL.
+func (h *Handler) gameSummaries(ctx context.Context, byColor bool) ([]db.MatchupSummary, []db.ResultSummary, time.Time, error) {
+	c := &h.summaries
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := 0
+	if byColor {
+		i = 1
+	}
+	e := &c.entries[i]
+	if !e.at.IsZero() && time.Since(e.at) < summaryTTL {
+		return e.matchups, e.results, e.at, nil
+	}
+
+	var matchups []db.MatchupSummary
+	var err error
+	if byColor {
+		matchups, err = h.store.MatchupSummariesByColor(ctx)
+	} else {
+		matchups, err = h.store.ListMatchupSummaries(ctx)
+	}
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	results, err := h.store.ListResultSummaries(ctx)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	*e = summaryEntry{matchups: matchups, results: results, at: time.Now()}
+	return matchups, results, e.at, nil
+}
+
+// invalidate makes the next games page load fresh summaries.
+func (c *summaryCache) invalidate() {
+	c.mu.Lock()
+	c.entries = [2]summaryEntry{}
+	c.mu.Unlock()
+}
+
+func (h *Handler) handleGamesRefresh(w http.ResponseWriter, r *http.Request) {
+	h.summaries.invalidate()
+	// back to the same view of the games page
+	target := "/games"
+	if q := r.FormValue("query"); q != "" {
+		target += "?" + q
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// storedTimeLayouts are the formats timestamps are stored in: the
+// strftime('%Y-%m-%dT%H:%M:%fZ') column default, and SQLite's plain
+// CURRENT_TIMESTAMP form.
+var storedTimeLayouts = []string{
+	"2006-01-02T15:04:05.000Z",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+}
+
+func parseStoredTime(s string) (time.Time, bool) {
+	for _, layout := range storedTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (h *Handler) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"fmtTime":    h.fmtTime,
+		"ago":        h.ago,
+		"duration":   fmtDuration,
+		"gameStatus": gameStatus,
+		// siteTitle names the instance in page titles and the header
+		"siteTitle": func() string { return h.siteTitle },
+		// adminLinks is false on public pages when admin has its own listener
+		"adminLinks": func() bool { return !h.separateAdmin },
+	}
+}
+
+// fmtDuration renders a millisecond total in its two largest units.
+func fmtDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", ms)
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Hour:
+		return fmt.Sprintf("%dm %02ds", int(d/time.Minute), int(d%time.Minute/time.Second))
+	default:
+		return fmt.Sprintf("%dh %02dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+}
+
+// fmtTime renders a stored timestamp in the display time zone; unparseable
+// values are shown as-is.
+func (h *Handler) fmtTime(s string) string {
+	t, ok := parseStoredTime(s)
+	if !ok {
+		return s
+	}
+	return t.In(h.loc).Format("2006-01-02 15:04:05 MST")
+}
+
+// ago renders a stored timestamp relative to now, falling back to the date
+// for anything older than a week.
+func (h *Handler) ago(s string) string {
+	t, ok := parseStoredTime(s)
+	if !ok {
+		return s
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	default:
+		return t.In(h.loc).Format("2006-01-02")
+	}
+}
@@ -1,10 +1,15 @@
 package web
 
 import (
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"html/template"
 	"io/fs"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"tethys/internal/db"
 	"tethys/internal/engine"
@@ -23,63 +28,220 @@ type Handler struct {
 	an         *engine.Analyzer
 	enginesDir string
 	booksDir   string
+	// suitesDir holds EPD test suites that can be imported
+	suitesDir string
+	// engine paths must be under one of these (or enginesDir); empty allows any
+	engineDirs []string
 
 	tpl *template.Template
+	// tplDir, if set, re-parses templates from disk on every render
+	tplDir string
+	// loc is the time zone timestamps are displayed in
+	loc *time.Location
+	// started is reported as uptime by /api/status
+	started time.Time
+	// siteTitle replaces "tethys" in page titles and the header
+	siteTitle string
+	// robotsDisallow are the path prefixes /robots.txt asks crawlers to skip
+	robotsDisallow []string
+	// separateAdmin is set when the admin pages are served on a listener of
+	// their own; public pages then neither link to them nor offer admin actions
+	separateAdmin bool
+
+	summaries summaryCache
+
+	// UCI option lines from each engine's last successful test, by engine
+	// ID; kept in memory only, so they are gone after a restart
+	optionsMu     sync.Mutex
+	engineOptions map[int64][]string
 }
 
-func NewHandler(store *db.Store, r *engine.Runner, b *engine.Broadcaster, an *engine.Analyzer, enginesDir string, booksDir string) *Handler {
-	tpl := template.Must(template.New("base").ParseFS(templatesFS, "templates/*.html"))
-	return &Handler{
+func NewHandler(store *db.Store, r *engine.Runner, b *engine.Broadcaster, an *engine.Analyzer, enginesDir string, booksDir string, suitesDir string, engineDirs []string) *Handler {
+	h := &Handler{
 		store:      store,
 		r:          r,
 		b:          b,
 		an:         an,
 		enginesDir: enginesDir,
 		booksDir:   booksDir,
-		tpl:        tpl,
+		suitesDir:  suitesDir,
+		engineDirs: engineDirs,
+		loc:        time.UTC,
+		started:    time.Now(),
+
+		siteTitle:      "tethys",
+		robotsDisallow: DefaultRobotsDisallow,
+
+		engineOptions: make(map[int64][]string),
 	}
+	h.tpl = template.Must(h.parseTemplates(templatesFS, "templates/*.html"))
+	return h
 }
 
-func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	staticSub, err := fs.Sub(staticFS, "static")
-	if err != nil {
-		panic(err)
+func (h *Handler) parseTemplates(fsys fs.FS, pattern string) (*template.Template, error) {
+	return template.New("base").Funcs(h.templateFuncs()).ParseFS(fsys, pattern)
+}
+
+// SetLocation sets the time zone rendered timestamps are shown in.
+func (h *Handler) SetLocation(loc *time.Location) {
+	h.loc = loc
+}
+
+// SetSiteTitle sets the name shown in page titles and the header.
+func (h *Handler) SetSiteTitle(title string) {
+	h.siteTitle = title
+}
+
+// SetRobotsDisallow sets the path prefixes /robots.txt disallows; none
+// allows everything.
+func (h *Handler) SetRobotsDisallow(paths []string) {
+	h.robotsDisallow = paths
+}
+
+// SetTemplateDir makes the handler load templates from dir on every render
+// instead of the embedded copies, so template edits show up without a
+// rebuild. For development only.
+func (h *Handler) SetTemplateDir(dir string) error {
+	if _, err := h.parseTemplates(os.DirFS(dir), "*.html"); err != nil {
+		return err
 	}
-	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(staticSub)))
+	h.tplDir = dir
+	return nil
+}
+
+// templates returns the embedded templates, or a fresh parse in dev mode.
+func (h *Handler) templates() (*template.Template, error) {
+	if h.tplDir == "" {
+		return h.tpl, nil
+	}
+	return h.parseTemplates(os.DirFS(h.tplDir), "*.html")
+}
+
+// RegisterRoutes serves the public site and the admin pages on one mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	h.registerPublic(mux)
+	h.registerAdmin(mux)
+}
+
+// RegisterAdminRoutes serves only the admin pages, for a separate admin
+// listener. The root redirects to the admin settings.
+func (h *Handler) RegisterAdminRoutes(mux *http.ServeMux) {
+	registerStatic(mux)
+	mux.Handle("GET /{$}", noIndex(http.HandlerFunc(h.handleAdminRoot)))
+	h.registerAdmin(mux)
+}
+
+// RegisterPublicRoutes serves everything except the admin pages and actions,
+// which RegisterAdminRoutes serves on another listener.
+func (h *Handler) RegisterPublicRoutes(mux *http.ServeMux) {
+	h.separateAdmin = true
+	h.registerPublic(mux)
+}
+
+func (h *Handler) registerPublic(mux *http.ServeMux) {
+	registerStatic(mux)
 
 	mux.HandleFunc("GET /{$}", h.handleIndex)
+	mux.HandleFunc("GET /robots.txt", h.handleRobots)
 	mux.HandleFunc("GET /live/fragment", h.handleLiveFragment)
 	mux.HandleFunc("GET /live/queue", h.handleQueueFragment)
 	mux.HandleFunc("GET /live/recent", h.handleRecentGamesFragment)
+	mux.HandleFunc("GET /live/board.svg", h.handleBoardSVG)
 	mux.Handle("GET /api/live/events", engine.SSEHandler(h.b))
 	mux.HandleFunc("GET /api/live", h.handleLiveJSON)
+	mux.HandleFunc("GET /api/live/all", h.handleLiveAllJSON)
+	mux.HandleFunc("GET /api/status", h.handleStatusJSON)
+	mux.HandleFunc("GET /readyz", h.handleReadyz)
+	mux.HandleFunc("GET /api/engines", h.handleEnginesJSON)
 	mux.HandleFunc("GET /opening", h.handleOpeningPage)
 	mux.HandleFunc("GET /opening/fragment", h.handleOpeningFragment)
+	mux.HandleFunc("GET /api/opening", h.handleOpeningJSON)
+	mux.HandleFunc("GET /api/games/{id}/position", h.handleGamePositionJSON)
 	mux.HandleFunc("GET /book", h.handleBookExplorer)
 	mux.HandleFunc("GET /results", h.handleResults)
-	mux.HandleFunc("POST /results/recompute", h.handleRankingRecompute)
+	mux.HandleFunc("GET /engine/{id}", h.handleEnginePage)
+	mux.HandleFunc("GET /suites", h.handleSuiteResults)
 	mux.HandleFunc("GET /positions/view", h.handlePositionView)
 	mux.HandleFunc("GET /api/positions/eval", h.handlePositionEval)
 	mux.HandleFunc("GET /api/positions/move", h.handlePositionMove)
+	mux.HandleFunc("GET /api/position/moves", h.handlePositionLegalMoves)
+	mux.HandleFunc("GET /api/positions/games", h.handlePositionGames)
 
 	mux.HandleFunc("GET /games", h.handleGames)
 	mux.HandleFunc("GET /games/matchup.txt", h.handleMatchupMoves)
 	mux.HandleFunc("GET /games/result.txt", h.handleResultDownload)
+	mux.HandleFunc("GET /games/result.pgn", h.handleResultPGN)
+	mux.HandleFunc("GET /download/all.txt", h.handleDownloadAll)
+	mux.HandleFunc("GET /engine/{id}/games.txt", h.handleEngineGamesTxt)
+	mux.HandleFunc("GET /engine/{id}/games.pgn", h.handleEngineGamesPGN)
 	mux.HandleFunc("GET /games/", h.handleGameMoves) // /games/{id}.txt
 	mux.HandleFunc("GET /games/view", h.handleGameView)
+}
+
+// registerAdmin adds the admin pages, all marked noindex for crawlers, and
+// the actions on public pages that change or delete data.
+func (h *Handler) registerAdmin(parent *http.ServeMux) {
+	mux := noIndexMux{parent}
+	mux.HandleFunc("POST /results/recompute", h.handleRankingRecompute)
+	mux.HandleFunc("POST /games/refresh", h.handleGamesRefresh)
 	mux.HandleFunc("POST /games/delete", h.handleMatchupDelete)
 	mux.HandleFunc("POST /games/delete-result", h.handleResultDelete)
-
 	mux.HandleFunc("GET /admin", h.handleAdminRoot)
 	mux.HandleFunc("GET /admin/settings", h.handleAdminSettings)
 	mux.HandleFunc("POST /admin/settings", h.handleAdminSettingsSave)
 	mux.HandleFunc("GET /admin/matches", h.handleAdminMatches)
+	mux.HandleFunc("POST /admin/boards/abort", h.handleAdminBoardAbort)
+	mux.HandleFunc("POST /admin/queue/reset", h.handleAdminQueueReset)
+	mux.HandleFunc("POST /admin/queue/quick-match", h.handleAdminQuickMatch)
 	mux.HandleFunc("GET /admin/engines", h.handleAdminEngines)
 	mux.HandleFunc("POST /admin/engines", h.handleAdminEnginesSave)
 	mux.HandleFunc("POST /admin/engines/duplicate", h.handleAdminEngineDuplicate)
 	mux.HandleFunc("POST /admin/engines/rename", h.handleAdminEngineRename)
 	mux.HandleFunc("POST /admin/engines/add-unused", h.handleAdminEngineAddUnused)
 	mux.HandleFunc("POST /admin/engines/delete-unused", h.handleAdminEngineDeleteUnused)
+	mux.HandleFunc("POST /admin/engines/delete-unused-all", h.handleAdminEngineDeleteAllUnused)
 	mux.HandleFunc("POST /admin/engines/prune", h.handleAdminEnginePrune)
+	mux.HandleFunc("POST /admin/engines/merge", h.handleAdminEngineMerge)
+	mux.HandleFunc("POST /admin/engines/pause", h.handleAdminEnginePause)
+	mux.HandleFunc("POST /admin/engines/bench", h.handleAdminEngineBench)
+	mux.HandleFunc("POST /admin/engines/test", h.handleAdminEngineTest)
+	mux.HandleFunc("GET /admin/suites", h.handleAdminSuites)
+	mux.HandleFunc("POST /admin/suites/import", h.handleAdminSuiteImport)
+	mux.HandleFunc("POST /admin/suites/run", h.handleAdminSuiteRun)
+	mux.HandleFunc("GET /admin/suites/view", h.handleAdminSuiteView)
+	mux.HandleFunc("GET /admin/audit", h.handleAdminAudit)
 	mux.HandleFunc("POST /admin/logout", h.handleAdminLogout)
 }
+
+func registerStatic(mux *http.ServeMux) {
+	staticSub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /static/", http.StripPrefix("/static/", staticHandler(staticSub)))
+}
+
+// embedded files have no modification time, so give each one an ETag from its
+// content; http.FileServer then answers If-None-Match with 304.
+func staticHandler(sub fs.FS) http.Handler {
+	etags := map[string]string{}
+	_ = fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(sub, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		etags[name] = `"` + hex.EncodeToString(sum[:8]) + `"`
+		return nil
+	})
+	files := http.FileServerFS(sub)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag, ok := etags[r.URL.Path]; ok {
+			w.Header().Set("ETag", etag)
+		}
+		files.ServeHTTP(w, r)
+	})
+}